@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// AudioOptions configures audio-only downloads
+type AudioOptions struct {
+	Format     string // wav, mp3, m4a or flac
+	SampleRate int    // Hz; 0 keeps the source rate
+	Channels   int    // 0 keeps the source layout
+	Normalize  bool   // EBU R128 loudness normalization to loudnessTarget
+}
+
+// audioFormats maps each output format to its ffmpeg codec options
+var audioFormats = map[string][]string{
+	"wav":  {"-c:a", "pcm_s16le"},
+	"mp3":  {"-c:a", "libmp3lame", "-q:a", "2"},
+	"m4a":  {"-c:a", "aac", "-b:a", "192k", "-movflags", "+faststart"},
+	"flac": {"-c:a", "flac"},
+}
+
+// Loudness targets for normalization: -16 LUFS integrated is the usual podcast level
+const (
+	loudnessTarget   = -16.0
+	truePeakTarget   = -1.5
+	loudnessRangeMax = 11.0
+	normalizeRateHz  = 48000 // loudnorm resamples to 192 kHz internally, so an output rate is always set
+)
+
+// defaultAudioFormat is uncompressed since most audio downloads are transcribed or edited
+const defaultAudioFormat = "wav"
+
+// Validate checks the format and ranges before anything is downloaded
+func (a *AudioOptions) Validate() error {
+	if _, ok := audioFormats[a.Format]; !ok {
+		var names []string
+		for name := range audioFormats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown audio format %q (available: %s)", a.Format, strings.Join(names, ", "))
+	}
+	if a.SampleRate < 0 || a.Channels < 0 {
+		return fmt.Errorf("sample rate and channels must not be negative")
+	}
+	return nil
+}
+
+// ffmpegArgs returns the output options that turn any input into this audio format
+func (a *AudioOptions) ffmpegArgs() []string {
+	args := []string{"-vn"}
+	if a.Normalize {
+		args = append(args, "-af", fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g", loudnessTarget, truePeakTarget, loudnessRangeMax))
+	}
+
+	sampleRate := a.SampleRate
+	if sampleRate == 0 && a.Normalize {
+		sampleRate = normalizeRateHz
+	}
+	if sampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(sampleRate))
+	}
+	if a.Channels > 0 {
+		args = append(args, "-ac", strconv.Itoa(a.Channels))
+	}
+	return append(args, audioFormats[a.Format]...)
+}
+
+// ConvertAudio extracts the audio of input into output with these options, keeping only
+// timeRange when it is set
+func (a *AudioOptions) ConvertAudio(input, output string, timeRange *TimeRange) error {
+	args := []string{"-y", "-i", input}
+	if timeRange != nil {
+		args = append(args, timeRange.FFmpegArgs()...)
+	}
+	args = append(args, a.ffmpegArgs()...)
+	args = append(args, output)
+
+	cmd := exec.Command("ffmpeg", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error converting audio: %v", err)
+	}
+	return nil
+}
+
+// DownloadAudio downloads only the audio of a video and converts it to opts.Audio's format.
+// The output is named from the name template like a video download, so it never overwrites
+// an earlier file, and gets a metadata sidecar.
+func DownloadAudio(provider Provider, videoURL string, opts DownloadOptions) (string, error) {
+	audio := opts.Audio
+	if audio == nil {
+		audio = &AudioOptions{Format: defaultAudioFormat}
+	}
+	if err := audio.Validate(); err != nil {
+		return "", err
+	}
+	EnsureOutputDir("output")
+
+	outputFile, metadata, err := ResolveOutputPath(videoURL, opts.NameTemplate, provider, audio.Format, opts.nameSuffix())
+	if err != nil {
+		return "", fmt.Errorf("error naming output file: %v", err)
+	}
+	defer ReleaseOutputPath(outputFile)
+
+	// Fetch the source audio, cut to the range by yt-dlp where possible
+	var sourceFile string
+	trimRange := opts.TimeRange
+	if isDirectMediaURL(videoURL) {
+		workDir, file, err := fetchDirectSource(videoURL, 0, opts.Connections)
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(workDir)
+		sourceFile = file
+	} else {
+		sourceFile = fmt.Sprintf("./output/%s_temp_audio.m4a", uuid.New().String())
+		defer CleanUpFiles(sourceFile)
+
+		fmt.Printf("Downloading audio from %s: %s\n", provider.Name(), videoURL)
+		trimmed, err := runYTDLP(videoURL, provider.AudioFormat(), sourceFile, opts.TimeRange)
+		if err != nil {
+			return "", fmt.Errorf("error downloading audio: %v", err)
+		}
+		if trimmed {
+			trimRange = nil
+		}
+	}
+
+	original, err := ProbeMedia(sourceFile)
+	if err != nil {
+		fmt.Printf("⚠️ Could not probe %s: %v\n", sourceFile, err)
+	}
+	if err := audio.ConvertAudio(sourceFile, outputFile, trimRange); err != nil {
+		return "", err
+	}
+
+	if err := WriteSidecar(outputFile, videoURL, metadata, original); err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+	}
+	opts.downloadCaptionsFor(videoURL, outputFile)
+
+	fmt.Printf("Audio downloaded and saved as %s\n", outputFile)
+	return outputFile, nil
+}
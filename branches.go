@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// BranchStatus describes a local branch relative to its upstream and the default branch
+type BranchStatus struct {
+	Name           string    `json:"name"`
+	Current        bool      `json:"current"`
+	Upstream       string    `json:"upstream,omitempty"`
+	AheadUpstream  int       `json:"aheadUpstream"`
+	BehindUpstream int       `json:"behindUpstream"`
+	AheadDefault   int       `json:"aheadDefault"`
+	BehindDefault  int       `json:"behindDefault"`
+	LastCommit     time.Time `json:"lastCommit"`
+	Author         string    `json:"author"`
+	Merged         bool      `json:"merged"`
+}
+
+// BranchFilter selects which branches ListBranches reports
+type BranchFilter struct {
+	Pattern  string // glob matched against the branch name
+	Merged   bool   // only branches merged into the default branch
+	Unmerged bool   // only branches not merged into the default branch
+}
+
+// ListBranches collects the status of every local branch against its upstream and the default branch
+func ListBranches(filter BranchFilter) ([]BranchStatus, string, error) {
+	defaultBranch, err := DefaultBranch()
+	if err != nil {
+		return nil, "", err
+	}
+
+	output, err := runGit("for-each-ref",
+		"--format=%(refname:short)%09%(upstream:short)%09%(committerdate:unix)%09%(authorname)%09%(HEAD)",
+		"refs/heads")
+	if err != nil {
+		return nil, "", fmt.Errorf("error listing branches: %v", err)
+	}
+
+	var branches []BranchStatus
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+		// runGit trims the output, which can drop the blank %(HEAD) column of the last line
+		fields := strings.Split(line, "\t")
+		if len(fields) == 4 {
+			fields = append(fields, "")
+		}
+		if len(fields) != 5 {
+			return nil, "", fmt.Errorf("unexpected for-each-ref output: %q", line)
+		}
+
+		branch := BranchStatus{
+			Name:     fields[0],
+			Upstream: fields[1],
+			Author:   fields[3],
+			Current:  fields[4] == "*",
+		}
+		if filter.Pattern != "" {
+			if ok, _ := filepath.Match(filter.Pattern, branch.Name); !ok {
+				continue
+			}
+		}
+
+		if unix, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			branch.LastCommit = time.Unix(unix, 0)
+		}
+
+		if branch.Upstream != "" {
+			// The upstream may have been deleted on the remote; leave the counts at zero then
+			if ahead, behind, err := aheadBehind(branch.Name, branch.Upstream); err == nil {
+				branch.AheadUpstream, branch.BehindUpstream = ahead, behind
+			}
+		}
+
+		if branch.Name != defaultBranch {
+			branch.AheadDefault, branch.BehindDefault, err = aheadBehind(branch.Name, defaultBranch)
+			if err != nil {
+				return nil, "", fmt.Errorf("error comparing %s with %s: %v", branch.Name, defaultBranch, err)
+			}
+		}
+		branch.Merged = isMergedInto(branch.Name, defaultBranch)
+
+		if filter.Merged && !branch.Merged || filter.Unmerged && branch.Merged {
+			continue
+		}
+		branches = append(branches, branch)
+	}
+
+	return branches, defaultBranch, nil
+}
+
+// SortBranches orders branches in place by name, date, ahead or behind.
+// Dates and counts sort descending so the most relevant branches come first.
+func SortBranches(branches []BranchStatus, key string) error {
+	var less func(a, b BranchStatus) bool
+	switch key {
+	case "name":
+		less = func(a, b BranchStatus) bool { return a.Name < b.Name }
+	case "date":
+		less = func(a, b BranchStatus) bool { return a.LastCommit.After(b.LastCommit) }
+	case "ahead":
+		less = func(a, b BranchStatus) bool { return a.AheadDefault > b.AheadDefault }
+	case "behind":
+		less = func(a, b BranchStatus) bool { return a.BehindDefault > b.BehindDefault }
+	default:
+		return fmt.Errorf("unknown sort key %q (use name, date, ahead or behind)", key)
+	}
+
+	sort.SliceStable(branches, func(i, j int) bool { return less(branches[i], branches[j]) })
+	return nil
+}
+
+// PrintBranches writes the branch dashboard as a table, or as JSON when asJSON is set
+func PrintBranches(branches []BranchStatus, defaultBranch string, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(branches)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\tBRANCH\tUPSTREAM\t+/- UPSTREAM\t+/- %s\tLAST COMMIT\tAUTHOR\tMERGED\n", defaultBranch)
+	for _, b := range branches {
+		marker := ""
+		if b.Current {
+			marker = "*"
+		}
+		upstream, upstreamCounts := "-", "-"
+		if b.Upstream != "" {
+			upstream = b.Upstream
+			upstreamCounts = fmt.Sprintf("+%d/-%d", b.AheadUpstream, b.BehindUpstream)
+		}
+		merged := "no"
+		if b.Merged {
+			merged = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t+%d/-%d\t%s\t%s\t%s\n",
+			marker, b.Name, upstream, upstreamCounts, b.AheadDefault, b.BehindDefault,
+			b.LastCommit.Format("2006-01-02"), b.Author, merged)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// captionCue is one timed block of a subtitle file
+type captionCue struct {
+	Start float64
+	End   float64
+	Lines []string
+}
+
+var captionTag = regexp.MustCompile(`<[^>]*>`)
+
+// parseVTTCues reads the cues of a WebVTT file, stripping styling and inline timing tags
+func parseVTTCues(content string) ([]captionCue, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var cues []captionCue
+	for _, block := range strings.Split(content, "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+
+		// The timing line may follow an optional cue identifier
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue // WEBVTT header, NOTE or STYLE block
+		}
+
+		fields := strings.Fields(lines[timing])
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid cue timing %q", lines[timing])
+		}
+		start, err := ParseTimestamp(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := ParseTimestamp(fields[2])
+		if err != nil {
+			return nil, err
+		}
+
+		cue := captionCue{Start: start, End: end}
+		for _, line := range lines[timing+1:] {
+			text := strings.TrimSpace(html.UnescapeString(captionTag.ReplaceAllString(line, "")))
+			if text != "" {
+				cue.Lines = append(cue.Lines, text)
+			}
+		}
+		cues = append(cues, cue)
+	}
+	return cues, nil
+}
+
+// dedupeRollingCues drops the lines auto-generated captions repeat from cue to cue as the
+// text scrolls up, keeping each spoken line once in the cue where it first appears
+func dedupeRollingCues(cues []captionCue) []captionCue {
+	var result []captionCue
+	var recent []string
+
+	for _, cue := range cues {
+		var fresh []string
+		for _, line := range cue.Lines {
+			seen := false
+			for _, r := range recent {
+				if r == line {
+					seen = true
+					break
+				}
+			}
+			if seen {
+				continue
+			}
+			fresh = append(fresh, line)
+			recent = append(recent, line)
+			if len(recent) > 3 {
+				recent = recent[1:]
+			}
+		}
+		if len(fresh) > 0 {
+			result = append(result, captionCue{Start: cue.Start, End: cue.End, Lines: fresh})
+		}
+	}
+	return result
+}
+
+// formatSRTTime renders seconds as HH:MM:SS,mmm
+func formatSRTTime(seconds float64) string {
+	ms := int(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// renderSRT writes cues as an SRT document
+func renderSRT(cues []captionCue) string {
+	var b strings.Builder
+	for i, cue := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, formatSRTTime(cue.Start), formatSRTTime(cue.End), strings.Join(cue.Lines, "\n"))
+	}
+	return b.String()
+}
+
+// fetchVTT downloads manual subtitles in lang, falling back to auto-generated ones,
+// and returns the path of the VTT file
+func fetchVTT(videoURL, lang, tempDir string) (string, bool, error) {
+	base := filepath.Join(tempDir, uuid.New().String())
+
+	for _, auto := range []bool{false, true} {
+		args := []string{"--skip-download", "--sub-langs", lang, "--sub-format", "vtt/best", "--convert-subs", "vtt", "-o", base + ".%(ext)s"}
+		if auto {
+			args = append(args, "--write-auto-subs")
+		} else {
+			args = append(args, "--write-subs")
+		}
+
+		args = append(args, ytDLPAuthArgs(videoURL)...)
+		cmd := exec.Command("yt-dlp", append(args, videoURL)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return "", false, fmt.Errorf("error downloading captions: %v", err)
+		}
+
+		matches, _ := filepath.Glob(base + "*.vtt")
+		if len(matches) > 0 {
+			return matches[0], auto, nil
+		}
+	}
+	return "", false, fmt.Errorf("no %s captions available", lang)
+}
+
+// clipCues keeps the cues inside a time range and shifts them to start at zero
+func clipCues(cues []captionCue, timeRange *TimeRange) []captionCue {
+	var clipped []captionCue
+	for _, cue := range cues {
+		if cue.End <= timeRange.Start || (timeRange.End > 0 && cue.Start >= timeRange.End) {
+			continue
+		}
+		cue.Start = math.Max(cue.Start, timeRange.Start) - timeRange.Start
+		if timeRange.End > 0 {
+			cue.End = math.Min(cue.End, timeRange.End)
+		}
+		cue.End -= timeRange.Start
+		clipped = append(clipped, cue)
+	}
+	return clipped
+}
+
+// DownloadCaptions fetches captions for a video and stores them in the transcript cache as
+// output/transcriptions/<name>.txt, .srt, .vtt and .json, where name is the video file's base name.
+// The .txt is what GenerateTitlesAndDescriptions reads, so Whisper is skipped for that video.
+// With a time range only the captions of that section are kept, timed from its start.
+func DownloadCaptions(videoURL, lang, name string, timeRange *TimeRange) (string, error) {
+	EnsureOutputDir("output")
+	tempDir, err := os.MkdirTemp("./output", "captions_")
+	if err != nil {
+		return "", fmt.Errorf("failed to create captions directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	fmt.Printf("💬 Downloading %s captions...\n", lang)
+	vttFile, auto, err := fetchVTT(videoURL, lang, tempDir)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(vttFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read captions: %v", err)
+	}
+	cues, err := parseVTTCues(string(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse captions: %v", err)
+	}
+	if auto {
+		cues = dedupeRollingCues(cues)
+	}
+	if timeRange != nil {
+		cues = clipCues(cues, timeRange)
+	}
+	if len(cues) == 0 {
+		return "", fmt.Errorf("captions are empty")
+	}
+
+	transcript := transcriptFromCues(cues)
+	transcript.Language = lang
+	transcript.Backend = transcriptSourceCaptions
+	files, err := SaveTranscript(transcript, name)
+	if err != nil {
+		return "", err
+	}
+
+	kind := "manual"
+	if auto {
+		kind = "auto-generated"
+	}
+	fmt.Printf("✅ Saved %s captions to %s\n", kind, strings.Join(files, ", "))
+	return files[0], nil
+}
+
+// captionNameFor returns the transcript cache name of a downloaded media file
+func captionNameFor(mediaPath string) string {
+	return stripFileExtension(filepath.Base(mediaPath))
+}
+
+// DownloadCaptionsOnly stores captions under the name the video itself would get,
+// so a later download or publish of the video finds them
+func DownloadCaptionsOnly(videoURL string, opts DownloadOptions) (string, error) {
+	provider := ProviderFor(videoURL)
+	template := opts.NameTemplate
+	if template == "" {
+		template = NameTemplateFor(provider.Name())
+	}
+
+	metadata, err := provider.FetchMetadata(videoURL)
+	if err != nil {
+		return "", err
+	}
+	name, err := RenderNameTemplate(template, metadata, "mp4")
+	if err != nil {
+		return "", err
+	}
+	return DownloadCaptions(videoURL, opts.CaptionLang, captionNameFor(name)+opts.nameSuffix(), opts.TimeRange)
+}
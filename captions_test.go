@@ -0,0 +1,124 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVTTCues(t *testing.T) {
+	content := "WEBVTT\r\nKind: captions\r\nLanguage: en\r\n\r\n" +
+		"NOTE produced by a test\r\n\r\n" +
+		"STYLE\r\n::cue { color: white }\r\n\r\n" +
+		"intro\r\n00:00:01.000 --> 00:00:02.500 align:start position:0%\r\nHello <c.colorE5E5E5>there</c>\r\n\r\n" +
+		"00:01.000 --> 00:04.000\r\nfish &amp; chips<00:00:03.000><c> now</c>\r\nsecond line\r\n\r\n" +
+		"00:00:05.000 --> 00:00:06.000\r\n \r\n"
+	want := []captionCue{
+		{Start: 1, End: 2.5, Lines: []string{"Hello there"}},
+		{Start: 1, End: 4, Lines: []string{"fish & chips now", "second line"}},
+		{Start: 5, End: 6},
+	}
+	got, err := parseVTTCues(content)
+	if err != nil {
+		t.Fatalf("parseVTTCues returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseVTTCues() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseVTTCuesInvalidTiming(t *testing.T) {
+	for _, content := range []string{
+		"WEBVTT\n\n00:00:01.000 -->\ntext\n",
+		"WEBVTT\n\nxx:00 --> 00:00:02.000\ntext\n",
+		"WEBVTT\n\n00:00:01.000 --> yy\ntext\n",
+	} {
+		if _, err := parseVTTCues(content); err == nil {
+			t.Errorf("parseVTTCues(%q) returned no error", content)
+		}
+	}
+}
+
+func TestDedupeRollingCues(t *testing.T) {
+	tests := []struct {
+		name string
+		cues []captionCue
+		want []captionCue
+	}{
+		{"empty", nil, nil},
+		{
+			"rolling auto captions",
+			[]captionCue{
+				{Start: 0, End: 2, Lines: []string{"hello everyone"}},
+				{Start: 2, End: 2.01, Lines: []string{"hello everyone"}},
+				{Start: 2, End: 4, Lines: []string{"hello everyone", "welcome back"}},
+				{Start: 4, End: 6, Lines: []string{"welcome back", "to the show"}},
+			},
+			[]captionCue{
+				{Start: 0, End: 2, Lines: []string{"hello everyone"}},
+				{Start: 2, End: 4, Lines: []string{"welcome back"}},
+				{Start: 4, End: 6, Lines: []string{"to the show"}},
+			},
+		},
+		{
+			"a line repeated much later is kept",
+			[]captionCue{
+				{Start: 0, End: 1, Lines: []string{"yes"}},
+				{Start: 1, End: 2, Lines: []string{"a"}},
+				{Start: 2, End: 3, Lines: []string{"b"}},
+				{Start: 3, End: 4, Lines: []string{"c"}},
+				{Start: 4, End: 5, Lines: []string{"yes"}},
+			},
+			[]captionCue{
+				{Start: 0, End: 1, Lines: []string{"yes"}},
+				{Start: 1, End: 2, Lines: []string{"a"}},
+				{Start: 2, End: 3, Lines: []string{"b"}},
+				{Start: 3, End: 4, Lines: []string{"c"}},
+				{Start: 4, End: 5, Lines: []string{"yes"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := dedupeRollingCues(tt.cues); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: dedupeRollingCues() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClipCues(t *testing.T) {
+	cues := []captionCue{
+		{Start: 0, End: 5, Lines: []string{"before"}},
+		{Start: 8, End: 12, Lines: []string{"straddles start"}},
+		{Start: 15, End: 18, Lines: []string{"inside"}},
+		{Start: 19, End: 25, Lines: []string{"straddles end"}},
+		{Start: 30, End: 35, Lines: []string{"after"}},
+	}
+	want := []captionCue{
+		{Start: 0, End: 2, Lines: []string{"straddles start"}},
+		{Start: 5, End: 8, Lines: []string{"inside"}},
+		{Start: 9, End: 10, Lines: []string{"straddles end"}},
+	}
+	if got := clipCues(cues, &TimeRange{Start: 10, End: 20}); !reflect.DeepEqual(got, want) {
+		t.Errorf("clipCues() = %+v, want %+v", got, want)
+	}
+
+	open := clipCues(cues, &TimeRange{Start: 20})
+	if len(open) != 2 || open[0].Start != 0 || open[0].End != 5 || open[1].Start != 10 {
+		t.Errorf("clipCues() without an end = %+v", open)
+	}
+}
+
+func TestFormatSRTTime(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "00:00:00,000"},
+		{3723.25, "01:02:03,250"},
+		{59.9996, "00:01:00,000"},
+	}
+	for _, tt := range tests {
+		if got := formatSRTTime(tt.seconds); got != tt.want {
+			t.Errorf("formatSRTTime(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
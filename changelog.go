@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// conventionalSubject matches "type(scope)!: summary"
+var conventionalSubject = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+
+// changelogSections lists the rendered sections in order with the commit types they collect
+var changelogSections = []struct {
+	Title string
+	Type  string
+}{
+	{"Features", "feat"},
+	{"Bug Fixes", "fix"},
+	{"Performance", "perf"},
+	{"Refactoring", "refactor"},
+	{"Documentation", "docs"},
+	{"Tests", "test"},
+	{"Build", "build"},
+	{"CI", "ci"},
+	{"Style", "style"},
+	{"Chores", "chore"},
+	{"Reverts", "revert"},
+	{"Other Changes", ""},
+}
+
+// ChangelogEntry is a single commit parsed as a conventional commit
+type ChangelogEntry struct {
+	Hash     string
+	Type     string // empty when the subject is not a conventional commit
+	Scope    string
+	Subject  string
+	Breaking bool
+}
+
+// ChangelogCommits parses the commits reachable from to but not from from.
+// An empty from means the most recent tag before to, or the whole history if there is none.
+func ChangelogCommits(from, to string) ([]ChangelogEntry, string, error) {
+	if from == "" {
+		if tag, err := runGit("describe", "--tags", "--abbrev=0", to+"^"); err == nil {
+			from = tag
+		}
+	}
+
+	revRange := to
+	if from != "" {
+		revRange = from + ".." + to
+	}
+
+	// Unit and record separators keep multi-line bodies intact
+	output, err := runGit("log", "--no-merges", "--format=%h%x1f%s%x1f%b%x1e", revRange)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading commits for %s: %v", revRange, err)
+	}
+
+	var entries []ChangelogEntry
+	for _, record := range strings.Split(output, "\x1e") {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x1f", 3)
+		if len(fields) < 2 {
+			continue
+		}
+
+		body := ""
+		if len(fields) == 3 {
+			body = fields[2]
+		}
+		entries = append(entries, parseChangelogEntry(fields[0], fields[1], body))
+	}
+
+	return entries, revRange, nil
+}
+
+// parseChangelogEntry reads a commit's subject as a conventional commit. Subjects that aren't
+// one are kept whole with an empty type.
+func parseChangelogEntry(hash, subject, body string) ChangelogEntry {
+	entry := ChangelogEntry{Hash: hash, Subject: subject}
+	if match := conventionalSubject.FindStringSubmatch(subject); match != nil {
+		entry.Type = strings.ToLower(match[1])
+		entry.Scope = match[2]
+		entry.Breaking = match[3] == "!"
+		entry.Subject = match[4]
+	}
+	if strings.Contains(body, "BREAKING CHANGE") {
+		entry.Breaking = true
+	}
+	return entry
+}
+
+// formatChangelogEntry renders one commit as a Markdown list item
+func formatChangelogEntry(entry ChangelogEntry) string {
+	if entry.Scope != "" {
+		return fmt.Sprintf("- **%s:** %s (%s)\n", entry.Scope, entry.Subject, entry.Hash)
+	}
+	return fmt.Sprintf("- %s (%s)\n", entry.Subject, entry.Hash)
+}
+
+// RenderChangelog groups entries by conventional commit type under a version heading
+func RenderChangelog(version string, entries []ChangelogEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s (%s)\n", version, time.Now().Format("2006-01-02"))
+
+	var breaking []ChangelogEntry
+	for _, entry := range entries {
+		if entry.Breaking {
+			breaking = append(breaking, entry)
+		}
+	}
+	if len(breaking) > 0 {
+		b.WriteString("\n### ⚠ Breaking Changes\n\n")
+		for _, entry := range breaking {
+			b.WriteString(formatChangelogEntry(entry))
+		}
+	}
+
+	known := map[string]bool{}
+	for _, section := range changelogSections {
+		known[section.Type] = true
+	}
+
+	for _, section := range changelogSections {
+		var items []string
+		for _, entry := range entries {
+			entryType := entry.Type
+			if !known[entryType] {
+				entryType = "" // unknown types land in Other Changes
+			}
+			if entryType == section.Type {
+				items = append(items, formatChangelogEntry(entry))
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", section.Title)
+		for _, item := range items {
+			b.WriteString(item)
+		}
+	}
+
+	return b.String()
+}
+
+// PolishChangelog asks GPT to tidy the wording of a rendered changelog without changing its structure
+func PolishChangelog(markdown string) (string, error) {
+	log.Println("🤖 Polishing changelog using GPT...")
+	polished, err := RequestChatCompletion([]GPTMessage{
+		{Role: "system", Content: "You edit release notes for clarity."},
+		{Role: "user", Content: "Improve the wording of this changelog so each entry reads clearly to users. " +
+			"Keep every heading, entry and commit hash, keep the Markdown structure, and do not invent changes. " +
+			"Return ONLY the Markdown, without code fences or commentary.\n\n" + markdown},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to polish changelog: %v", err)
+	}
+	return stripCodeFence(polished) + "\n", nil
+}
+
+// PrependChangelog inserts the new section at the top of the changelog file,
+// below a leading "# " title if the file has one
+func PrependChangelog(path, section string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	content := string(existing)
+	header := "# Changelog\n\n"
+	if strings.HasPrefix(content, "# ") {
+		end := strings.Index(content, "\n")
+		if end < 0 {
+			end = len(content)
+		}
+		header = strings.TrimSpace(content[:end]) + "\n\n"
+		content = content[end:]
+	}
+	content = strings.TrimLeft(content, "\n")
+
+	updated := header + strings.TrimSpace(section) + "\n"
+	if content != "" {
+		updated += "\n" + content
+	}
+
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	fmt.Printf("✅ Changelog prepended to %s\n", path)
+	return nil
+}
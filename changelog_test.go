@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseChangelogEntry(t *testing.T) {
+	tests := []struct {
+		subject, body string
+		want          ChangelogEntry
+	}{
+		{"feat: add x", "", ChangelogEntry{Type: "feat", Subject: "add x"}},
+		{"fix(api): handle y", "", ChangelogEntry{Type: "fix", Scope: "api", Subject: "handle y"}},
+		{"Feat(ui)!: drop z", "", ChangelogEntry{Type: "feat", Scope: "ui", Subject: "drop z", Breaking: true}},
+		{"refactor: move w", "BREAKING CHANGE: w moved", ChangelogEntry{Type: "refactor", Subject: "move w", Breaking: true}},
+		{"Update README", "", ChangelogEntry{Subject: "Update README"}},
+		{"feat add x", "", ChangelogEntry{Subject: "feat add x"}},
+	}
+	for _, tt := range tests {
+		tt.want.Hash = "abc1234"
+		if got := parseChangelogEntry("abc1234", tt.subject, tt.body); got != tt.want {
+			t.Errorf("parseChangelogEntry(%q, %q) = %+v, want %+v", tt.subject, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestRenderChangelog(t *testing.T) {
+	entries := []ChangelogEntry{
+		{Hash: "a1", Type: "fix", Subject: "handle y"},
+		{Hash: "b2", Type: "feat", Scope: "ui", Subject: "drop z", Breaking: true},
+		{Hash: "c3", Type: "wip", Subject: "try things"},
+		{Hash: "d4", Subject: "Update README"},
+		{Hash: "e5", Type: "feat", Subject: "add x"},
+	}
+	want := "## v1.2.0 (" + time.Now().Format("2006-01-02") + ")\n" +
+		"\n### ⚠ Breaking Changes\n\n" +
+		"- **ui:** drop z (b2)\n" +
+		"\n### Features\n\n" +
+		"- **ui:** drop z (b2)\n" +
+		"- add x (e5)\n" +
+		"\n### Bug Fixes\n\n" +
+		"- handle y (a1)\n" +
+		"\n### Other Changes\n\n" +
+		"- try things (c3)\n" +
+		"- Update README (d4)\n"
+	if got := RenderChangelog("v1.2.0", entries); got != want {
+		t.Errorf("RenderChangelog() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderChangelogSkipsEmptySections(t *testing.T) {
+	got := RenderChangelog("v0.1.0", []ChangelogEntry{{Hash: "a1", Type: "docs", Subject: "explain x"}})
+	if strings.Contains(got, "Breaking") || strings.Contains(got, "Features") {
+		t.Errorf("RenderChangelog rendered empty sections:\n%s", got)
+	}
+	if !strings.Contains(got, "\n### Documentation\n\n- explain x (a1)\n") {
+		t.Errorf("RenderChangelog is missing the documentation entry:\n%s", got)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// maxDiffChars is roughly how much diff text fits in one chat request alongside the prompt.
+// Larger diffs are summarized file by file before the final request.
+const maxDiffChars = 12000
+
+// fileDiff is the part of a unified diff that belongs to a single file
+type fileDiff struct {
+	Path string
+	Diff string
+}
+
+// splitDiffByFile breaks a unified diff into per-file sections on its "diff --git" headers
+func splitDiffByFile(diff string) []fileDiff {
+	var files []fileDiff
+	for _, section := range strings.Split(diff, "\ndiff --git ") {
+		section = strings.TrimPrefix(section, "diff --git ")
+		if strings.TrimSpace(section) == "" {
+			continue
+		}
+
+		// The header reads "a/<path> b/<path>"; take the b side so renames report the new name
+		header := strings.SplitN(section, "\n", 2)[0]
+		path := header
+		if i := strings.LastIndex(header, " b/"); i >= 0 {
+			path = header[i+3:]
+		}
+		files = append(files, fileDiff{Path: path, Diff: "diff --git " + section})
+	}
+	return files
+}
+
+// condenseDiff returns the diff unchanged when it fits in a request.
+// Otherwise each file's diff is summarized by GPT and the summaries are returned instead.
+func condenseDiff(diff string) (string, error) {
+	if len(diff) <= maxDiffChars {
+		return diff, nil
+	}
+
+	files := splitDiffByFile(diff)
+	// Progress goes to stderr so commands that print their result to stdout stay pipeable
+	log.Printf("🧩 Diff is too large for one request, summarizing %d files...", len(files))
+
+	var summaries []string
+	for i, file := range files {
+		fileText := file.Diff
+		if len(fileText) > maxDiffChars {
+			fileText = fileText[:maxDiffChars] + "\n... (diff truncated)"
+		}
+
+		log.Printf("Summarizing %s (%d/%d)", file.Path, i+1, len(files))
+		summary, err := RequestChatCompletion([]GPTMessage{
+			{Role: "system", Content: "You summarize code changes for other developers. Be concise and factual."},
+			{Role: "user", Content: fmt.Sprintf(
+				"Summarize the following diff of %s in at most three short bullet points. "+
+					"Describe what changed and why it matters, not line numbers.\n\n%s", file.Path, fileText)},
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize diff of %s: %v", file.Path, err)
+		}
+		summaries = append(summaries, fmt.Sprintf("File: %s\n%s", file.Path, strings.TrimSpace(summary)))
+	}
+
+	return strings.Join(summaries, "\n\n"), nil
+}
+
+// stripCodeFence removes a surrounding markdown code fence from a GPT reply
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if i := strings.Index(text, "\n"); i >= 0 {
+		text = text[i+1:] // drop the language tag line
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// GenerateCommitMessage proposes a conventional commit message for the staged changes
+func GenerateCommitMessage() (string, error) {
+	diff, err := runGit("diff", "--cached")
+	if err != nil {
+		return "", fmt.Errorf("error reading staged diff: %v", err)
+	}
+	if diff == "" {
+		return "", fmt.Errorf("no staged changes to describe")
+	}
+
+	changes, err := condenseDiff(diff)
+	if err != nil {
+		return "", err
+	}
+
+	log.Println("🤖 Generating commit message using GPT...")
+	message, err := RequestChatCompletion([]GPTMessage{
+		{Role: "system", Content: "You write git commit messages following the Conventional Commits specification."},
+		{Role: "user", Content: "Write a commit message for these staged changes.\n" +
+			"The subject line must be `<type>(<optional scope>): <summary>` with type one of " +
+			"feat, fix, docs, style, refactor, perf, test, build, ci or chore, in the imperative mood and under 72 characters. " +
+			"Add a blank line and a short body only if the change needs explaining.\n" +
+			"Return ONLY the commit message, without code fences or commentary.\n\n" + changes},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate commit message: %v", err)
+	}
+
+	return stripCodeFence(message), nil
+}
+
+// editInEditor opens text in $EDITOR (vi by default) and returns the saved result
+func editInEditor(text string) (string, error) {
+	file, err := os.CreateTemp("", "tools-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write temporary file: %v", err)
+	}
+	file.Close()
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// EDITOR may carry arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], file.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor exited with error: %v", err)
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited file: %v", err)
+	}
+	return strings.TrimSpace(string(edited)), nil
+}
+
+// CommitWithGeneratedMessage generates a message for the staged changes and lets the user
+// accept, edit or regenerate it before committing
+func CommitWithGeneratedMessage() error {
+	message, err := GenerateCommitMessage()
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("\n📝 Proposed commit message:\n\n%s\n\n", message)
+		fmt.Print("[a]ccept, [e]dit, [r]egenerate or [q]uit? ")
+		choice, err := reader.ReadString('\n')
+		if err != nil {
+			// Closed or non-interactive stdin would otherwise ask forever
+			return fmt.Errorf("no answer, nothing committed: %v", err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(choice)) {
+		case "a", "accept":
+			cmd := exec.Command("git", "commit", "-F", "-")
+			cmd.Stdin = strings.NewReader(message + "\n")
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("error committing: %v", err)
+			}
+			fmt.Println("✅ Changes committed!")
+			return nil
+		case "e", "edit":
+			edited, err := editInEditor(message)
+			if err != nil {
+				return err
+			}
+			if edited == "" {
+				fmt.Println("⚠️ Empty commit message, keeping the previous one.")
+				continue
+			}
+			message = edited
+		case "r", "regenerate":
+			message, err = GenerateCommitMessage()
+			if err != nil {
+				return err
+			}
+		case "q", "quit":
+			fmt.Println("Aborted, nothing committed.")
+			return nil
+		default:
+			fmt.Println("Please enter a, e, r or q.")
+		}
+	}
+}
+
+// WriteCommitMessageForHook is run by the prepare-commit-msg hook. It fills in the message file
+// only for plain commits, leaving merges, amends and -m/-F messages untouched.
+func WriteCommitMessageForHook(messageFile, source string) error {
+	if source != "" {
+		return nil
+	}
+
+	message, err := GenerateCommitMessage()
+	if err != nil {
+		return err
+	}
+
+	// Keep the comment lines git put in the template below the generated message
+	existing, err := os.ReadFile(messageFile)
+	if err != nil {
+		return fmt.Errorf("failed to read commit message file: %v", err)
+	}
+	content := message + "\n" + string(existing)
+	if err := os.WriteFile(messageFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write commit message file: %v", err)
+	}
+	return nil
+}
+
+// shellQuote wraps s in single quotes for sh, so spaces, $ and backslashes stay literal
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// InstallCommitMsgHook writes a prepare-commit-msg hook that calls this binary in hook mode.
+// A failure in the hook never blocks the commit; git just opens the usual empty template.
+func InstallCommitMsgHook() error {
+	hooksDir, err := runGit("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return fmt.Errorf("error locating hooks directory: %v", err)
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %v", err)
+	}
+
+	hookPath := filepath.Join(hooksDir, "prepare-commit-msg")
+	if _, err := os.Stat(hookPath); err == nil {
+		return fmt.Errorf("%s already exists; remove it first to install the generated hook", hookPath)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate tools executable: %v", err)
+	}
+
+	script := fmt.Sprintf("#!/bin/sh\n# Installed by tools commit-msg -install-hook\n%s commit-msg -hook \"$1\" \"$2\" || true\n", shellQuote(executable))
+	if err := os.WriteFile(hookPath, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write hook: %v", err)
+	}
+
+	fmt.Printf("✅ Installed prepare-commit-msg hook at %s\n", hookPath)
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitDiffByFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		diff  string
+		paths []string
+	}{
+		{"empty", "", nil},
+		{
+			"two files",
+			"diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n" +
+				"diff --git a/go.mod b/go.mod\n--- a/go.mod\n+++ b/go.mod\n",
+			[]string{"main.go", "go.mod"},
+		},
+		{
+			"rename reports the new name",
+			"diff --git a/old/name.go b/new/name.go\nsimilarity index 100%\nrename from old/name.go\nrename to new/name.go\n",
+			[]string{"new/name.go"},
+		},
+	}
+	for _, tt := range tests {
+		files := splitDiffByFile(tt.diff)
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, file.Path)
+			if !strings.HasPrefix(file.Diff, "diff --git ") {
+				t.Errorf("%s: diff of %s lost its header: %q", tt.name, file.Path, file.Diff)
+			}
+		}
+		if !reflect.DeepEqual(paths, tt.paths) {
+			t.Errorf("%s: paths = %v, want %v", tt.name, paths, tt.paths)
+		}
+	}
+}
+
+func TestSplitDiffByFileKeepsContent(t *testing.T) {
+	first := "diff --git a/a.txt b/a.txt\n@@ -1 +1 @@\n-x\n+y"
+	second := "diff --git a/b.txt b/b.txt\n@@ -1 +1 @@\n-1\n+2\n"
+	files := splitDiffByFile(first + "\n" + second)
+	if len(files) != 2 || files[0].Diff != first || files[1].Diff != second {
+		t.Errorf("splitDiffByFile did not keep each file's diff intact: %#v", files)
+	}
+}
+
+func TestCondenseDiffKeepsSmallDiffs(t *testing.T) {
+	diff := "diff --git a/a.txt b/a.txt\n@@ -1 +1 @@\n-x\n+y\n"
+	got, err := condenseDiff(diff)
+	if err != nil {
+		t.Fatalf("condenseDiff returned error: %v", err)
+	}
+	if got != diff {
+		t.Errorf("condenseDiff changed a diff that fits in one request: %q", got)
+	}
+}
+
+func TestStripCodeFence(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"feat: add x", "feat: add x"},
+		{"```\nfeat: add x\n```", "feat: add x"},
+		{"```text\nfix(api): handle y\n\nBody line\n```\n", "fix(api): handle y\n\nBody line"},
+		{"  plain with spaces  ", "plain with spaces"},
+	}
+	for _, tt := range tests {
+		if got := stripCodeFence(tt.text); got != tt.want {
+			t.Errorf("stripCodeFence(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for _, path := range []string{
+		"/usr/local/bin/tools",
+		"/Users/Jane Doe/bin/tools",
+		"/opt/it's $HOME/`tools`\\bin",
+	} {
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(path)).Output()
+		if err != nil {
+			t.Fatalf("sh failed for %q: %v", path, err)
+		}
+		if string(out) != path {
+			t.Errorf("sh read shellQuote(%q) as %q", path, out)
+		}
+	}
+}
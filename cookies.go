@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// authCookieNames are the session cookies whose expiry decides whether a provider login still works
+var authCookieNames = map[string][]string{
+	"youtube": {"SID", "__Secure-1PSID", "__Secure-3PSID", "LOGIN_INFO"},
+	"x":       {"auth_token", "ct0"},
+}
+
+// CookieStatus is the result of checking a cookie file. It carries cookie names and
+// expiry dates only; cookie values are never read into it so they cannot end up in logs.
+type CookieStatus struct {
+	Provider  string
+	File      string
+	Cookies   int                  // cookie lines parsed from the file
+	Missing   []string             // expected auth cookies not present in the file
+	Expired   map[string]time.Time // auth cookies past their expiry
+	ExpiresAt time.Time            // earliest expiry of the remaining auth cookies
+}
+
+// OK reports whether the file holds cookies, including every auth cookie, and none has expired
+func (s *CookieStatus) OK() bool {
+	return s.Cookies > 0 && len(s.Missing) == 0 && len(s.Expired) == 0
+}
+
+// CookieFileFor returns the cookie file configured for a provider with COOKIES_FILE_<PROVIDER>,
+// falling back to COOKIES_FILE
+func CookieFileFor(provider string) string {
+	if provider != "" {
+		if path := os.Getenv("COOKIES_FILE_" + strings.ToUpper(provider)); path != "" {
+			return path
+		}
+	}
+	return os.Getenv("COOKIES_FILE")
+}
+
+// ValidateCookieFile checks a Netscape-format cookie file for the provider's auth cookies
+func ValidateCookieFile(provider, path string) (*CookieStatus, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cookie file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	// expiry per cookie name; 0 marks a session cookie
+	expiries := map[string]int64{}
+	cookies := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimPrefix(scanner.Text(), "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			continue
+		}
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
+		}
+		cookies++
+		name := fields[5]
+		if current, ok := expiries[name]; !ok || expiry > current {
+			expiries[name] = expiry
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookie file %s: %v", path, err)
+	}
+
+	status := &CookieStatus{Provider: provider, File: path, Cookies: cookies, Expired: map[string]time.Time{}}
+	now := time.Now()
+	for _, name := range authCookieNames[provider] {
+		expiry, ok := expiries[name]
+		if !ok {
+			status.Missing = append(status.Missing, name)
+			continue
+		}
+		if expiry == 0 {
+			continue
+		}
+		expiresAt := time.Unix(expiry, 0)
+		if expiresAt.Before(now) {
+			status.Expired[name] = expiresAt
+		} else if status.ExpiresAt.IsZero() || expiresAt.Before(status.ExpiresAt) {
+			status.ExpiresAt = expiresAt
+		}
+	}
+	return status, nil
+}
+
+// describeCookieStatus summarizes a check in one line
+func describeCookieStatus(status *CookieStatus) string {
+	if status.OK() {
+		if status.ExpiresAt.IsZero() {
+			return fmt.Sprintf("✅ %s cookies (%s) look valid", status.Provider, status.File)
+		}
+		return fmt.Sprintf("✅ %s cookies (%s) valid until %s", status.Provider, status.File, status.ExpiresAt.Format("2006-01-02"))
+	}
+
+	var problems []string
+	if status.Cookies == 0 {
+		problems = append(problems, "no cookies in Netscape format")
+	}
+	if len(status.Missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(status.Missing, ", "))
+	}
+	var expired []string
+	for name, at := range status.Expired {
+		expired = append(expired, fmt.Sprintf("%s on %s", name, at.Format("2006-01-02")))
+	}
+	sort.Strings(expired)
+	if len(expired) > 0 {
+		problems = append(problems, "expired "+strings.Join(expired, ", "))
+	}
+	return fmt.Sprintf("❌ %s cookies (%s) need refreshing: %s", status.Provider, status.File, strings.Join(problems, "; "))
+}
+
+// checkedCookieFiles makes sure each cookie file is validated once per run, not once per yt-dlp call
+var (
+	checkedCookieFiles   = map[string]bool{}
+	checkedCookieFilesMu sync.Mutex
+)
+
+// ytDLPAuthArgs returns the yt-dlp options that authenticate requests for the URL: the provider's
+// cookie file and, with YTDLP_NETRC set, netrc credentials (YTDLP_NETRC_LOCATION picks the file).
+// Only file paths are passed and printed, never cookie contents.
+func ytDLPAuthArgs(videoURL string) []string {
+	var args []string
+
+	provider := ProviderFor(videoURL).Name()
+	if cookieFile := CookieFileFor(provider); cookieFile != "" {
+		checkedCookieFilesMu.Lock()
+		firstUse := !checkedCookieFiles[cookieFile]
+		checkedCookieFiles[cookieFile] = true
+		checkedCookieFilesMu.Unlock()
+
+		if firstUse {
+			if status, err := ValidateCookieFile(provider, cookieFile); err != nil {
+				fmt.Printf("⚠️ %v\n", err)
+			} else if !status.OK() {
+				fmt.Println(describeCookieStatus(status))
+			}
+		}
+		args = append(args, "--cookies", cookieFile)
+	}
+
+	if netrc := os.Getenv("YTDLP_NETRC"); netrc != "" && netrc != "0" && netrc != "false" {
+		args = append(args, "--netrc")
+		if location := os.Getenv("YTDLP_NETRC_LOCATION"); location != "" {
+			args = append(args, "--netrc-location", location)
+		}
+	}
+	return args
+}
+
+// CheckCookies validates the cookie file of every provider that has one configured and
+// returns an error if any of them needs refreshing. Providers without known auth cookies
+// only have their file checked for being readable and holding cookies.
+func CheckCookies() error {
+	var names []string
+	for _, p := range providers {
+		names = append(names, p.Name())
+	}
+	names = append(names, defaultProvider.Name())
+
+	checked, failed := 0, 0
+	for _, provider := range names {
+		path := CookieFileFor(provider)
+		if path == "" {
+			continue
+		}
+		checked++
+
+		status, err := ValidateCookieFile(provider, path)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Println(describeCookieStatus(status))
+		if !status.OK() {
+			failed++
+		}
+	}
+
+	if checked == 0 {
+		fmt.Println("No cookie files configured. Set COOKIES_FILE or COOKIES_FILE_<PROVIDER> (e.g. COOKIES_FILE_YOUTUBE) in .env.")
+		return nil
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d cookie file(s) need attention", failed)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeCookieFile writes a Netscape cookie file with one .youtube.com cookie per name and expiry
+func writeCookieFile(t *testing.T, cookies map[string]int64) string {
+	t.Helper()
+	content := "# Netscape HTTP Cookie File\n\n"
+	for name, expiry := range cookies {
+		content += fmt.Sprintf("#HttpOnly_.youtube.com\tTRUE\t/\tTRUE\t%d\t%s\tsecret-value\n", expiry, name)
+	}
+	content += "malformed line\n.youtube.com\tTRUE\t/\tTRUE\tnever\tSID\tx\n"
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateCookieFile(t *testing.T) {
+	soon := time.Now().Add(24 * time.Hour).Unix()
+	later := time.Now().Add(30 * 24 * time.Hour).Unix()
+	past := time.Now().Add(-24 * time.Hour).Unix()
+
+	tests := []struct {
+		name      string
+		cookies   map[string]int64
+		missing   []string
+		expired   []string
+		expiresAt int64
+	}{
+		{
+			"all present",
+			map[string]int64{"SID": later, "__Secure-1PSID": soon, "__Secure-3PSID": later, "LOGIN_INFO": 0},
+			nil, nil, soon,
+		},
+		{
+			"missing and expired",
+			map[string]int64{"SID": past, "__Secure-1PSID": later},
+			[]string{"__Secure-3PSID", "LOGIN_INFO"}, []string{"SID"}, later,
+		},
+	}
+	for _, tt := range tests {
+		status, err := ValidateCookieFile("youtube", writeCookieFile(t, tt.cookies))
+		if err != nil {
+			t.Fatalf("%s: ValidateCookieFile returned error: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(status.Missing, tt.missing) {
+			t.Errorf("%s: missing = %v, want %v", tt.name, status.Missing, tt.missing)
+		}
+		var expired []string
+		for name := range status.Expired {
+			expired = append(expired, name)
+		}
+		if !reflect.DeepEqual(expired, tt.expired) {
+			t.Errorf("%s: expired = %v, want %v", tt.name, expired, tt.expired)
+		}
+		if status.ExpiresAt.Unix() != tt.expiresAt {
+			t.Errorf("%s: expires at %v, want %v", tt.name, status.ExpiresAt, time.Unix(tt.expiresAt, 0))
+		}
+		if status.OK() != (tt.missing == nil && tt.expired == nil) {
+			t.Errorf("%s: OK() = %v", tt.name, status.OK())
+		}
+	}
+}
+
+func TestValidateCookieFileEmpty(t *testing.T) {
+	status, err := ValidateCookieFile("reddit", writeCookieFile(t, nil))
+	if err != nil {
+		t.Fatalf("ValidateCookieFile returned error: %v", err)
+	}
+	if status.Cookies != 0 || status.OK() {
+		t.Errorf("file without cookies: Cookies = %d, OK() = %v; want 0, false", status.Cookies, status.OK())
+	}
+}
+
+func TestCheckCookies(t *testing.T) {
+	for _, name := range []string{"", "_YOUTUBE", "_X", "_BLUESKY", "_REDDIT", "_GENERIC"} {
+		t.Setenv("COOKIES_FILE"+name, "")
+	}
+	if err := CheckCookies(); err != nil {
+		t.Errorf("CheckCookies with no files returned error: %v", err)
+	}
+
+	t.Setenv("COOKIES_FILE_REDDIT", writeCookieFile(t, map[string]int64{"session": 0}))
+	if err := CheckCookies(); err != nil {
+		t.Errorf("CheckCookies with a valid reddit file returned error: %v", err)
+	}
+	t.Setenv("COOKIES_FILE_BLUESKY", writeCookieFile(t, nil))
+	if err := CheckCookies(); err == nil {
+		t.Error("CheckCookies accepted a bluesky file without cookies")
+	}
+	t.Setenv("COOKIES_FILE_BLUESKY", "")
+	t.Setenv("COOKIES_FILE_GENERIC", filepath.Join(t.TempDir(), "missing.txt"))
+	if err := CheckCookies(); err == nil {
+		t.Error("CheckCookies accepted a missing generic file")
+	}
+}
+
+func TestValidateCookieFileMissing(t *testing.T) {
+	if _, err := ValidateCookieFile("youtube", filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("ValidateCookieFile accepted a missing file")
+	}
+}
+
+func TestCookieFileFor(t *testing.T) {
+	t.Setenv("COOKIES_FILE", "all.txt")
+	t.Setenv("COOKIES_FILE_X", "x.txt")
+	if got := CookieFileFor("x"); got != "x.txt" {
+		t.Errorf("CookieFileFor(x) = %q, want the provider's file", got)
+	}
+	if got := CookieFileFor("youtube"); got != "all.txt" {
+		t.Errorf("CookieFileFor(youtube) = %q, want the fallback", got)
+	}
+}
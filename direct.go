@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// directMediaExtensions are file types fetched with the native downloader instead of yt-dlp
+var directMediaExtensions = map[string]bool{
+	".mp4": true, ".m4v": true, ".mov": true, ".mkv": true, ".webm": true, ".ts": true,
+	".m4a": true, ".mp3": true, ".aac": true, ".wav": true, ".flac": true, ".ogg": true, ".opus": true,
+}
+
+// defaultConnections is how many ranges or HLS segments are fetched at once
+const defaultConnections = 8
+
+// isDirectMediaURL reports whether the URL is a plain media file or an HLS playlist
+func isDirectMediaURL(videoURL string) bool {
+	if isHLSURL(videoURL) {
+		return true
+	}
+	parsed, err := url.Parse(videoURL)
+	if err != nil {
+		return false
+	}
+	return directMediaExtensions[strings.ToLower(path.Ext(parsed.Path))]
+}
+
+// directMetadata names a direct download after the file in its URL, since there is no page to ask
+func directMetadata(videoURL string) *VideoMetadata {
+	metadata := &VideoMetadata{Extractor: "generic", WebpageURL: videoURL}
+	if parsed, err := url.Parse(videoURL); err == nil {
+		name := path.Base(parsed.Path)
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+		metadata.Title = strings.TrimSuffix(name, path.Ext(name))
+		metadata.ID = metadata.Title
+	}
+	return metadata
+}
+
+// remuxStreams copies the streams of one file (or a video-only and an audio-only file) into dest
+func remuxStreams(inputs []string, dest string) error {
+	args := []string{"-y"}
+	for _, input := range inputs {
+		args = append(args, "-i", input)
+	}
+	if len(inputs) > 1 {
+		args = append(args, "-map", "0:v", "-map", "1:a")
+	}
+	args = append(args, "-c", "copy", dest)
+
+	cmd := exec.Command("ffmpeg", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error remuxing %s: %v", strings.Join(inputs, ", "), err)
+	}
+	return nil
+}
+
+// fetchDirectSource downloads a direct media link or HLS playlist into a work directory under
+// ./output keyed by the URL and returns both paths. Partial downloads stay in the work
+// directory, so fetching the same URL again resumes them; remove it once the file is used.
+func fetchDirectSource(videoURL string, maxHeight, connections int) (string, string, error) {
+	if connections < 1 {
+		connections = defaultConnections
+	}
+
+	EnsureOutputDir("output")
+
+	hash := sha1.Sum([]byte(videoURL))
+	workDir := filepath.Join("./output", ".direct_"+hex.EncodeToString(hash[:6]))
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create download directory: %v", err)
+	}
+
+	var sourceFile string
+	if isHLSURL(videoURL) {
+		sourceFile = filepath.Join(workDir, "source.mkv")
+	} else {
+		parsed, _ := url.Parse(videoURL)
+		sourceFile = filepath.Join(workDir, "source"+strings.ToLower(path.Ext(parsed.Path)))
+	}
+
+	if _, err := os.Stat(sourceFile); err == nil {
+		fmt.Printf("⏯️ Already downloaded %s\n", videoURL)
+	} else if isHLSURL(videoURL) {
+		fmt.Printf("Downloading HLS stream: %s\n", videoURL)
+		if err := DownloadHLS(videoURL, workDir, sourceFile, maxHeight, connections); err != nil {
+			return "", "", fmt.Errorf("error downloading HLS stream: %v", err)
+		}
+	} else {
+		fmt.Printf("Downloading file: %s\n", videoURL)
+		if err := DownloadHTTP(videoURL, sourceFile, connections); err != nil {
+			return "", "", fmt.Errorf("error downloading file: %v", err)
+		}
+	}
+
+	return workDir, sourceFile, nil
+}
+
+// DownloadDirect downloads a direct media link or HLS playlist without yt-dlp and converts
+// it with the chosen preset (premiere by default). Partial downloads are kept in a work
+// directory under ./output keyed by the URL, so running the same download again resumes it.
+func DownloadDirect(videoURL string, opts DownloadOptions) (string, error) {
+	EnsureOutputDir("output")
+
+	preset := opts.Preset
+	if preset == nil {
+		var err error
+		if preset, err = GetPreset(DefaultPresetName); err != nil {
+			return "", err
+		}
+	}
+
+	workDir, sourceFile, err := fetchDirectSource(videoURL, preset.MaxHeight, opts.Connections)
+	if err != nil {
+		return "", err
+	}
+
+	template := opts.NameTemplate
+	if template == "" {
+		template = NameTemplateFor("generic")
+	}
+	metadata := directMetadata(videoURL)
+	outputFile, err := ReserveNamedOutputPath(template, metadata, preset.Container, opts.nameSuffix())
+	if err != nil {
+		return "", fmt.Errorf("error naming output file: %v", err)
+	}
+	defer ReleaseOutputPath(outputFile)
+
+	transcodePath, original, err := TranscodeWithPreset(sourceFile, outputFile, preset, opts.TimeRange)
+	if err != nil {
+		return "", err
+	}
+	if original != nil {
+		metadata.Duration = original.Duration
+	}
+
+	if err := os.RemoveAll(workDir); err != nil {
+		return "", fmt.Errorf("error cleaning up temporary files: %v", err)
+	}
+
+	if err := WriteSidecar(outputFile, videoURL, metadata, original); err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+	}
+	if opts.CaptionLang != "" {
+		fmt.Println("⚠️ Captions are not available for direct media links")
+	}
+
+	fmt.Printf("Video downloaded and saved as %s (%s)\n", outputFile, transcodePath)
+	return outputFile, nil
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DownloadQueueOptions configures RunDownloadQueue
+type DownloadQueueOptions struct {
+	DownloadOptions
+	Provider    Provider // nil picks the provider for each URL
+	Workers     int      // number of concurrent downloads
+	Retries     int      // extra attempts after the first failure
+	ArchivePath string   // file of already downloaded URLs; empty disables the archive
+}
+
+// DownloadReport is the outcome of one queued URL
+type DownloadReport struct {
+	URL        string
+	Attempts   int
+	Skipped    bool // already in the download archive or listed earlier in the queue
+	Duplicate  bool // skipped because the URL was listed earlier in the queue
+	OutputFile string
+	Err        error
+}
+
+// ReadURLList collects URLs from the arguments and from listFile ("-" for stdin).
+// With no arguments and no list file the URLs are read from stdin.
+// Blank lines and lines starting with # are ignored.
+func ReadURLList(args []string, listFile string) ([]string, error) {
+	urls := append([]string{}, args...)
+
+	var reader io.Reader
+	switch {
+	case listFile == "-" || (listFile == "" && len(args) == 0):
+		reader = os.Stdin
+	case listFile != "":
+		file, err := os.Open(listFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open URL list: %v", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	if reader != nil {
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			urls = append(urls, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read URL list: %v", err)
+		}
+	}
+
+	for _, u := range urls {
+		if _, err := url.ParseRequestURI(u); err != nil {
+			return nil, fmt.Errorf("invalid URL %q: %v", u, err)
+		}
+	}
+	return urls, nil
+}
+
+// DownloadArchive remembers which downloads succeeded, one archive key per line.
+// See DownloadOptions.archiveKey for how the download mode is recorded.
+type DownloadArchive struct {
+	path string
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// LoadDownloadArchive reads the archive file, treating a missing file as empty
+func LoadDownloadArchive(path string) (*DownloadArchive, error) {
+	archive := &DownloadArchive{path: path, seen: map[string]bool{}}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return archive, nil
+		}
+		return nil, fmt.Errorf("failed to read download archive: %v", err)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			archive.seen[line] = true
+		}
+	}
+	return archive, nil
+}
+
+// Contains reports whether the archive key was recorded before
+func (a *DownloadArchive) Contains(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.seen[key]
+}
+
+// Add records a successful download and appends its key to the archive file
+func (a *DownloadArchive) Add(key string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.seen[key] {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %v", err)
+	}
+	file, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open download archive: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintln(file, key); err != nil {
+		return fmt.Errorf("failed to write download archive: %v", err)
+	}
+	a.seen[key] = true
+	return nil
+}
+
+// archiveKey is what the archive records for a download of videoURL: the bare URL for a
+// plain full download, followed by the audio format, section and preset otherwise, so a
+// clip or audio extraction doesn't count as the full video and the other way round
+func (opts DownloadOptions) archiveKey(videoURL string) string {
+	parts := []string{videoURL}
+	if opts.Audio != nil {
+		parts = append(parts, "audio="+opts.Audio.Format)
+	}
+	if opts.TimeRange != nil {
+		parts = append(parts, "section="+opts.TimeRange.YTDLPSection())
+	}
+	if opts.Preset != nil {
+		parts = append(parts, "preset="+opts.Preset.Name)
+	}
+	return strings.Join(parts, " ")
+}
+
+// downloadWith fetches one URL through the provider, as audio only when opts.Audio is set,
+// and checks the result against the fingerprint index
+func downloadWith(provider Provider, videoURL string, opts DownloadOptions) (string, error) {
+	var outputFile string
+	var err error
+	if opts.Audio != nil {
+		outputFile, err = DownloadAudio(provider, videoURL, opts)
+	} else {
+		outputFile, err = provider.Download(videoURL, opts)
+	}
+	if err != nil {
+		return "", err
+	}
+	return handleDuplicate(outputFile, opts.Duplicates), nil
+}
+
+// RunDownloadQueue downloads the URLs with a bounded worker pool, retrying failures
+// with exponential backoff and skipping URLs already in the archive. A URL listed twice
+// is downloaded once; the later entries are reported as skipped duplicates.
+func RunDownloadQueue(urls []string, opts DownloadQueueOptions) ([]DownloadReport, error) {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+
+	var archive *DownloadArchive
+	if opts.ArchivePath != "" {
+		var err error
+		archive, err = LoadDownloadArchive(opts.ArchivePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	EnsureOutputDir("output")
+
+	reports := make([]DownloadReport, len(urls))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				reports[i] = downloadWithRetries(urls[i], opts, archive)
+			}
+		}()
+	}
+
+	listed := map[string]bool{}
+	for i, videoURL := range urls {
+		if listed[videoURL] {
+			fmt.Printf("⏭️ Listed twice, skipping: %s\n", videoURL)
+			reports[i] = DownloadReport{URL: videoURL, Skipped: true, Duplicate: true}
+			continue
+		}
+		listed[videoURL] = true
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return reports, nil
+}
+
+// downloadWithRetries runs one queued download, backing off 5s, 10s, 20s... between attempts
+func downloadWithRetries(videoURL string, opts DownloadQueueOptions, archive *DownloadArchive) DownloadReport {
+	report := DownloadReport{URL: videoURL}
+	key := opts.archiveKey(videoURL)
+	if archive != nil && archive.Contains(key) {
+		fmt.Printf("⏭️ Already downloaded, skipping: %s\n", videoURL)
+		report.Skipped = true
+		return report
+	}
+
+	provider := opts.Provider
+	if provider == nil {
+		provider = ProviderFor(videoURL)
+	}
+
+	backoff := 5 * time.Second
+	retries := opts.Retries
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		report.Attempts = attempt
+		report.OutputFile, report.Err = downloadWith(provider, videoURL, opts.DownloadOptions)
+		if report.Err == nil {
+			break
+		}
+		if attempt <= retries {
+			fmt.Printf("❌ Download failed (attempt %d/%d): %s: %v\nRetrying in %s...\n", attempt, retries+1, videoURL, report.Err, backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	if report.Err == nil && archive != nil {
+		if err := archive.Add(key); err != nil {
+			fmt.Printf("⚠️ %v\n", err)
+		}
+	}
+	return report
+}
+
+// PrintDownloadReport lists successes, skips and failures and returns the number of failures
+func PrintDownloadReport(reports []DownloadReport) int {
+	var succeeded, skipped, failed []DownloadReport
+	for _, r := range reports {
+		switch {
+		case r.Skipped:
+			skipped = append(skipped, r)
+		case r.Err != nil:
+			failed = append(failed, r)
+		default:
+			succeeded = append(succeeded, r)
+		}
+	}
+
+	fmt.Printf("\n📋 Download report: %d succeeded, %d skipped, %d failed\n", len(succeeded), len(skipped), len(failed))
+	for _, r := range succeeded {
+		fmt.Printf("✅ %s -> %s (attempts: %d)\n", r.URL, r.OutputFile, r.Attempts)
+	}
+	for _, r := range skipped {
+		if r.Duplicate {
+			fmt.Printf("⏭️ %s (listed twice)\n", r.URL)
+		} else {
+			fmt.Printf("⏭️ %s (in archive)\n", r.URL)
+		}
+	}
+	for _, r := range failed {
+		fmt.Printf("❌ %s (attempts: %d): %v\n", r.URL, r.Attempts, r.Err)
+	}
+	return len(failed)
+}
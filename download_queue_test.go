@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadURLList(t *testing.T) {
+	listFile := filepath.Join(t.TempDir(), "urls.txt")
+	content := "# queued for later\nhttps://example.com/a\n\n  https://example.com/b  \n"
+	if err := os.WriteFile(listFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		listFile string
+		want     []string
+		wantErr  bool
+	}{
+		{"arguments only", []string{"https://example.com/x"}, "", []string{"https://example.com/x"}, false},
+		{"list file", nil, listFile, []string{"https://example.com/a", "https://example.com/b"}, false},
+		{"arguments first", []string{"https://example.com/x"}, listFile, []string{"https://example.com/x", "https://example.com/a", "https://example.com/b"}, false},
+		{"missing file", nil, filepath.Join(t.TempDir(), "missing.txt"), nil, true},
+		{"invalid URL", []string{"not a url"}, "", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ReadURLList(tt.args, tt.listFile)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ReadURLList error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ReadURLList() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive", "downloaded.txt")
+	archive, err := LoadDownloadArchive(path)
+	if err != nil {
+		t.Fatalf("LoadDownloadArchive of a missing file returned error: %v", err)
+	}
+	if archive.Contains("https://example.com/a") {
+		t.Error("empty archive contains a URL")
+	}
+
+	for _, u := range []string{"https://example.com/a", "https://example.com/b", "https://example.com/a"} {
+		if err := archive.Add(u); err != nil {
+			t.Fatalf("Add(%q) returned error: %v", u, err)
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "https://example.com/a\nhttps://example.com/b\n" {
+		t.Errorf("archive file = %q, want each URL once", content)
+	}
+
+	reloaded, err := LoadDownloadArchive(path)
+	if err != nil {
+		t.Fatalf("LoadDownloadArchive returned error: %v", err)
+	}
+	if !reloaded.Contains("https://example.com/b") || reloaded.Contains("https://example.com/c") {
+		t.Error("reloaded archive does not match what was added")
+	}
+}
+
+func TestArchiveKey(t *testing.T) {
+	const u = "https://example.com/a"
+	tests := []struct {
+		name string
+		opts DownloadOptions
+		want string
+	}{
+		{"full download", DownloadOptions{}, u},
+		{"audio", DownloadOptions{Audio: &AudioOptions{Format: "mp3"}}, u + " audio=mp3"},
+		{"section", DownloadOptions{TimeRange: &TimeRange{Start: 60, End: 90}}, u + " section=*00:01:00-00:01:30"},
+		{"preset", DownloadOptions{Preset: &EncodingPreset{Name: "shorts"}}, u + " preset=shorts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.archiveKey(u); got != tt.want {
+				t.Errorf("archiveKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
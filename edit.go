@@ -0,0 +1,375 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// editPadding is kept around every cut so word onsets and trailing consonants aren't clipped
+const editPadding = 0.05
+
+const editFileHeader = `# Delete words or whole lines to cut them from the video, then save and close.
+# Keep the [timestamp] at the start of each line you keep and don't reword anything:
+# the remaining words are matched back to the transcript to find their timing.
+# Lines starting with # are ignored.
+`
+
+// editDir holds the editable transcripts, one per video
+const editDir = "./output/edits"
+
+// EditFileFor returns where the editable transcript of a video is kept
+func EditFileFor(videoFile string) string {
+	return filepath.Join(editDir, captionNameFor(videoFile)+".txt")
+}
+
+// ExportEditableTranscript renders the transcript with one line per segment, each starting
+// with the segment's start time
+func ExportEditableTranscript(t *Transcript) string {
+	var b strings.Builder
+	b.WriteString(editFileHeader)
+	for _, segment := range t.Segments {
+		var words []string
+		for _, word := range segmentWords(segment) {
+			words = append(words, word.Word)
+		}
+		fmt.Fprintf(&b, "\n[%s] %s", formatVTTTime(segment.Start), strings.Join(words, " "))
+	}
+	return b.String() + "\n"
+}
+
+// segmentWords returns the timed words of a segment. Segments without word timestamps get
+// estimated ones that split the segment by the length of each word; EditVideo warns when it
+// has to cut on those.
+func segmentWords(segment TranscriptSegment) []TranscriptWord {
+	if len(segment.Words) > 0 {
+		return segment.Words
+	}
+	fields := strings.Fields(segment.Text)
+	total := 0
+	for _, field := range fields {
+		total += len([]rune(field))
+	}
+
+	var words []TranscriptWord
+	position := segment.Start
+	for _, field := range fields {
+		length := (segment.End - segment.Start) * float64(len([]rune(field))) / float64(total)
+		words = append(words, TranscriptWord{Word: field, Start: position, End: position + length})
+		position += length
+	}
+	return words
+}
+
+// normalizeEditWord compares words ignoring case and punctuation, so deleting a comma
+// doesn't break the match
+func normalizeEditWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
+
+// ParseEditedTranscript matches an edited transcript back to the original and reports, for
+// every word of every segment, whether it was kept
+func ParseEditedTranscript(t *Transcript, edited string) ([][]bool, error) {
+	kept := make([][]bool, len(t.Segments))
+	for i, segment := range t.Segments {
+		kept[i] = make([]bool, len(segmentWords(segment)))
+	}
+
+	next := 0
+	for n, line := range strings.Split(strings.ReplaceAll(edited, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "[") || !strings.Contains(line, "]") {
+			return nil, fmt.Errorf("line %d has no [timestamp]; keep the timestamp at the start of each line", n+1)
+		}
+		tag, text, _ := strings.Cut(line[1:], "]")
+		start, err := ParseTimestamp(tag)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+
+		// Lines stay in order, so the segment is at or after the previous line's
+		index := -1
+		for i := next; i < len(t.Segments); i++ {
+			if formatVTTTime(t.Segments[i].Start) == formatVTTTime(start) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("line %d: no segment starts at %s, or lines were reordered", n+1, tag)
+		}
+		next = index + 1
+
+		// Each remaining word is the next matching word of the segment; skipped ones were deleted
+		words := segmentWords(t.Segments[index])
+		w := 0
+		for _, token := range strings.Fields(text) {
+			normalized := normalizeEditWord(token)
+			for w < len(words) && normalizeEditWord(words[w].Word) != normalized {
+				w++
+			}
+			if w == len(words) {
+				return nil, fmt.Errorf("line %d: %q isn't in the original line; only delete words, don't change them", n+1, token)
+			}
+			kept[index][w] = true
+			w++
+		}
+	}
+	return kept, nil
+}
+
+// KeepIntervals turns the kept words into the stretches of the video to keep. Runs of kept
+// words become one interval, including the pauses between them, padded by editPadding.
+func KeepIntervals(t *Transcript, kept [][]bool, duration float64) []SilenceInterval {
+	var intervals []SilenceInterval
+	var current *SilenceInterval
+	for i, segment := range t.Segments {
+		for j, word := range segmentWords(segment) {
+			if !kept[i][j] {
+				current = nil
+				continue
+			}
+			if current == nil {
+				intervals = append(intervals, SilenceInterval{Start: word.Start, End: word.End})
+				current = &intervals[len(intervals)-1]
+			} else {
+				current.End = word.End
+			}
+		}
+	}
+
+	// Pad, clamp and merge intervals the padding made overlap
+	var merged []SilenceInterval
+	for _, interval := range intervals {
+		interval.Start = max(interval.Start-editPadding, 0)
+		interval.End += editPadding
+		if duration > 0 {
+			interval.End = min(interval.End, duration)
+		}
+		if interval.End <= interval.Start {
+			continue
+		}
+		if n := len(merged); n > 0 && interval.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, interval.End)
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// remapTranscript keeps the kept words and moves them to their times in the cut video
+func remapTranscript(t *Transcript, kept [][]bool, intervals []SilenceInterval) *Transcript {
+	remap := func(at float64) float64 {
+		offset := 0.0
+		for _, interval := range intervals {
+			if at <= interval.End {
+				return offset + max(at-interval.Start, 0)
+			}
+			offset += interval.End - interval.Start
+		}
+		return offset
+	}
+
+	remapped := &Transcript{Language: t.Language, Backend: transcriptSourceImport}
+	var texts []string
+	for i, segment := range t.Segments {
+		var words []TranscriptWord
+		for j, word := range segmentWords(segment) {
+			if kept[i][j] {
+				words = append(words, TranscriptWord{Word: word.Word, Start: remap(word.Start), End: remap(word.End)})
+			}
+		}
+		if len(words) == 0 {
+			continue
+		}
+
+		var text []string
+		for _, word := range words {
+			text = append(text, word.Word)
+		}
+		newSegment := TranscriptSegment{Start: words[0].Start, End: words[len(words)-1].End, Text: strings.Join(text, " ")}
+		if len(segment.Words) > 0 {
+			newSegment.Words = words
+		}
+		remapped.Segments = append(remapped.Segments, newSegment)
+		texts = append(texts, newSegment.Text)
+	}
+	remapped.Text = strings.Join(texts, " ")
+	return remapped
+}
+
+// extractVideoFilter removes a -vf option from codec arguments and returns its value, since
+// a filtergraph can't be combined with -vf on the same stream
+func extractVideoFilter(args []string) ([]string, string) {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-vf" {
+			return append(append([]string{}, args[:i]...), args[i+2:]...), args[i+1]
+		}
+	}
+	return args, ""
+}
+
+// RenderKeepIntervals cuts the intervals out of videoFile and joins them into output with
+// the preset's codecs. Trimming on decoded frames keeps every cut frame-accurate.
+func RenderKeepIntervals(videoFile, output string, intervals []SilenceInterval, preset *EncodingPreset, info *MediaInfo) error {
+	codecArgs, videoFilter := extractVideoFilter(preset.CodecArgs(TranscodeFull))
+	withVideo := preset.VideoCodec != "" && (info == nil || info.VideoCodec != "")
+
+	var graph strings.Builder
+	var inputs strings.Builder
+	for i, interval := range intervals {
+		if withVideo {
+			fmt.Fprintf(&graph, "[0:v]trim=start=%.3f:end=%.3f,setpts=PTS-STARTPTS[v%d];\n", interval.Start, interval.End, i)
+			fmt.Fprintf(&inputs, "[v%d]", i)
+		}
+		fmt.Fprintf(&graph, "[0:a]atrim=start=%.3f:end=%.3f,asetpts=PTS-STARTPTS[a%d];\n", interval.Start, interval.End, i)
+		fmt.Fprintf(&inputs, "[a%d]", i)
+	}
+	video := 0
+	if withVideo {
+		video = 1
+	}
+	fmt.Fprintf(&graph, "%sconcat=n=%d:v=%d:a=1", inputs.String(), len(intervals), video)
+	maps := []string{"-map", "[a]"}
+	if withVideo {
+		if videoFilter != "" {
+			fmt.Fprintf(&graph, "[vcat][a];\n[vcat]%s[v]", videoFilter)
+		} else {
+			graph.WriteString("[v][a]")
+		}
+		maps = append([]string{"-map", "[v]"}, maps...)
+	} else {
+		graph.WriteString("[a]")
+	}
+
+	// Long edits produce graphs too big for the command line
+	graphFile := output + ".filter.txt"
+	if err := os.WriteFile(graphFile, []byte(graph.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write filtergraph: %v", err)
+	}
+	defer os.Remove(graphFile)
+
+	args := append([]string{"-y", "-i", videoFile, "-filter_complex_script", graphFile}, maps...)
+	args = append(args, codecArgs...)
+	cmd := exec.Command("ffmpeg", append(args, output)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error rendering edit: %v", err)
+	}
+	return nil
+}
+
+// EditOptions configures EditVideo
+type EditOptions struct {
+	Transcription TranscriptionOptions
+	Preset        *EncodingPreset
+	ExportOnly    bool // write the editable transcript and stop
+	NoEditor      bool // render the edit file as it is instead of opening $EDITOR
+	Reset         bool // start over from the full transcript, discarding earlier edits
+}
+
+// EditVideo runs the text-based editing workflow: the video is transcribed with word
+// timestamps, the transcript is exported to output/edits/<name>.txt and opened in $EDITOR,
+// and the words left in it are rendered as output/<name>_edited.<ext>. The cut video gets
+// the edited transcript, timed to match, so it doesn't need transcribing again.
+func EditVideo(videoFile string, opts EditOptions) (string, error) {
+	opts.Transcription.Words = true
+	transcript, err := TranscribeAudio(videoFile, opts.Transcription)
+	if err != nil {
+		return "", err
+	}
+	if len(transcript.Segments) == 0 {
+		return "", fmt.Errorf("the transcript of %s has no timestamps to edit with", videoFile)
+	}
+	if !transcript.HasWords() {
+		fmt.Printf("⚠️ The %s transcript of %s has no word timestamps. Cuts will be placed at estimated word times and may clip words; use a backend with word timing, e.g. -backend whisper.\n", transcript.Backend, videoFile)
+	}
+
+	editFile := EditFileFor(videoFile)
+	if opts.Reset {
+		if err := os.Remove(editFile); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to reset edits: %v", err)
+		}
+	}
+	edited, err := os.ReadFile(editFile)
+	if os.IsNotExist(err) {
+		edited = []byte(ExportEditableTranscript(transcript))
+		if err := os.MkdirAll(editDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create edits directory: %v", err)
+		}
+		if err := os.WriteFile(editFile, edited, 0644); err != nil {
+			return "", fmt.Errorf("failed to write editable transcript: %v", err)
+		}
+		fmt.Printf("📝 Editable transcript written to %s\n", editFile)
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read editable transcript: %v", err)
+	} else {
+		fmt.Printf("📝 Continuing edit in %s\n", editFile)
+	}
+	if opts.ExportOnly {
+		return editFile, nil
+	}
+
+	if !opts.NoEditor {
+		text, err := editInEditor(string(edited))
+		if err != nil {
+			return "", err
+		}
+		edited = []byte(text + "\n")
+		if err := os.WriteFile(editFile, edited, 0644); err != nil {
+			return "", fmt.Errorf("failed to save editable transcript: %v", err)
+		}
+	}
+
+	kept, err := ParseEditedTranscript(transcript, string(edited))
+	if err != nil {
+		return "", fmt.Errorf("%s: %v (use -reset to start over from the transcript)", editFile, err)
+	}
+	info, err := ProbeMedia(videoFile)
+	if err != nil {
+		fmt.Printf("⚠️ Could not probe %s: %v\n", videoFile, err)
+	}
+	var duration float64
+	if info != nil {
+		duration = info.Duration
+	}
+	intervals := KeepIntervals(transcript, kept, duration)
+	if len(intervals) == 0 {
+		return "", fmt.Errorf("every word was deleted, nothing to render")
+	}
+
+	var keptSeconds float64
+	for _, interval := range intervals {
+		keptSeconds += interval.End - interval.Start
+	}
+	fmt.Printf("✂️ Keeping %d interval(s), %s of %s\n", len(intervals), formatSRTTime(keptSeconds), formatSRTTime(duration))
+
+	output, err := ReserveOutputPath("./output", captionNameFor(videoFile)+"_edited."+opts.Preset.Container)
+	if err != nil {
+		return "", err
+	}
+	defer ReleaseOutputPath(output)
+	if err := RenderKeepIntervals(videoFile, output, intervals, opts.Preset, info); err != nil {
+		return "", err
+	}
+
+	remapped := remapTranscript(transcript, kept, intervals)
+	if _, err := SaveTranscript(remapped, captionNameFor(output)); err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+	} else if err := cacheTranscriptFor(output, remapped); err != nil {
+		fmt.Printf("⚠️ Could not cache transcript: %v\n", err)
+	}
+
+	fmt.Printf("✅ Edited video saved as %s\n", output)
+	return output, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// closeIntervals compares intervals to the millisecond, since padding adds float error
+func closeIntervals(a, b []SilenceInterval) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i].Start-b[i].Start) > 1e-3 || math.Abs(a[i].End-b[i].End) > 1e-3 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestExportEditableTranscript(t *testing.T) {
+	got := ExportEditableTranscript(sampleTranscript())
+	want := editFileHeader + "\n[00:00:00.000] Hello there.\n[00:00:02.000] General Kenobi!\n"
+	if got != want {
+		t.Errorf("ExportEditableTranscript() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSegmentWordsEstimatesTiming(t *testing.T) {
+	words := segmentWords(TranscriptSegment{Start: 10, End: 16, Text: "ab cdef"})
+	want := []TranscriptWord{{"ab", 10, 12}, {"cdef", 12, 16}}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("segmentWords() = %v, want %v", words, want)
+	}
+}
+
+func TestParseEditedTranscript(t *testing.T) {
+	transcript := sampleTranscript()
+	tests := []struct {
+		name   string
+		edited string
+		want   [][]bool
+	}{
+		{"unchanged", ExportEditableTranscript(transcript), [][]bool{{true, true}, {true, true}}},
+		{"word deleted", "[00:00:00.000] Hello\n[00:00:02.000] General Kenobi!\n", [][]bool{{true, false}, {true, true}}},
+		{"line deleted", "# comment\n\n[00:00:02.000] Kenobi!\n", [][]bool{{false, false}, {false, true}}},
+		{"case and punctuation ignored", "[00:00:00.000] hello, THERE\r\n[2] general\r\n", [][]bool{{true, true}, {true, false}}},
+		{"everything deleted", editFileHeader, [][]bool{{false, false}, {false, false}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseEditedTranscript(transcript, tt.edited)
+		if err != nil {
+			t.Errorf("%s: ParseEditedTranscript returned error: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseEditedTranscript() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseEditedTranscriptErrors(t *testing.T) {
+	transcript := sampleTranscript()
+	tests := []struct {
+		name, edited, message string
+	}{
+		{"timestamp removed", "Hello there.\n", "no [timestamp]"},
+		{"invalid timestamp", "[soon] Hello\n", "line 1"},
+		{"unknown timestamp", "[00:00:01.000] Hello\n", "no segment starts"},
+		{"lines reordered", "[00:00:02.000] General\n[00:00:00.000] Hello\n", "reordered"},
+		{"word changed", "[00:00:00.000] Hi there.\n", `"Hi" isn't in the original`},
+		{"words swapped", "[00:00:02.000] Kenobi! General\n", `"General" isn't in the original`},
+	}
+	for _, tt := range tests {
+		_, err := ParseEditedTranscript(transcript, tt.edited)
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: ParseEditedTranscript error = %v, want one mentioning %q", tt.name, err, tt.message)
+		}
+	}
+}
+
+func TestKeepIntervals(t *testing.T) {
+	transcript := sampleTranscript()
+	tests := []struct {
+		name     string
+		kept     [][]bool
+		duration float64
+		want     []SilenceInterval
+	}{
+		{"runs become padded intervals", [][]bool{{true, false}, {true, true}}, 10, []SilenceInterval{{0, 0.65}, {1.95, 3.3}}},
+		{"pauses inside a run are kept", [][]bool{{false, true}, {true, false}}, 10, []SilenceInterval{{0.65, 2.55}}},
+		{"clamped to the duration", [][]bool{{false, false}, {false, true}}, 3.28, []SilenceInterval{{2.55, 3.28}}},
+		{"nothing kept", [][]bool{{false, false}, {false, false}}, 10, nil},
+	}
+	for _, tt := range tests {
+		if got := KeepIntervals(transcript, tt.kept, tt.duration); !closeIntervals(got, tt.want) {
+			t.Errorf("%s: KeepIntervals() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestKeepIntervalsMergesOverlappingPadding(t *testing.T) {
+	transcript := &Transcript{Segments: []TranscriptSegment{{
+		Start: 0, End: 2, Text: "a b c",
+		Words: []TranscriptWord{{"a", 0.5, 1}, {"b", 1.02, 1.04}, {"c", 1.08, 1.5}},
+	}}}
+	got := KeepIntervals(transcript, [][]bool{{true, false, true}}, 0)
+	if want := []SilenceInterval{{0.45, 1.55}}; !closeIntervals(got, want) {
+		t.Errorf("KeepIntervals() = %v, want %v", got, want)
+	}
+}
+
+func TestRemapTranscript(t *testing.T) {
+	transcript := sampleTranscript()
+	kept := [][]bool{{true, false}, {true, true}}
+	intervals := []SilenceInterval{{0, 0.65}, {1.95, 3.3}}
+
+	got := remapTranscript(transcript, kept, intervals)
+	if got.Text != "Hello General Kenobi!" || got.Backend != transcriptSourceImport || got.Language != "en" {
+		t.Errorf("remapTranscript() = %+v", got)
+	}
+	wantWords := [][]TranscriptWord{
+		{{"Hello", 0, 0.6}},
+		{{"General", 0.7, 1.2}, {"Kenobi!", 1.3, 1.95}},
+	}
+	if len(got.Segments) != len(wantWords) {
+		t.Fatalf("remapTranscript() has %d segments, want %d", len(got.Segments), len(wantWords))
+	}
+	for i, segment := range got.Segments {
+		for j, word := range segment.Words {
+			want := wantWords[i][j]
+			if word.Word != want.Word || math.Abs(word.Start-want.Start) > 1e-9 || math.Abs(word.End-want.End) > 1e-9 {
+				t.Errorf("segment %d word %d = %+v, want %+v", i, j, word, want)
+			}
+		}
+		if segment.Start != segment.Words[0].Start || segment.End != segment.Words[len(segment.Words)-1].End {
+			t.Errorf("segment %d spans %v-%v, not its words", i, segment.Start, segment.End)
+		}
+	}
+}
+
+func TestRemapTranscriptWithoutWordTimestamps(t *testing.T) {
+	transcript := &Transcript{Segments: []TranscriptSegment{{Start: 0, End: 4, Text: "ab cd"}}}
+	got := remapTranscript(transcript, [][]bool{{false, true}}, []SilenceInterval{{2, 4}})
+	want := []TranscriptSegment{{Start: 0, End: 2, Text: "cd"}}
+	if !reflect.DeepEqual(got.Segments, want) {
+		t.Errorf("remapTranscript() segments = %+v, want %+v", got.Segments, want)
+	}
+}
+
+func TestExtractVideoFilter(t *testing.T) {
+	args, filter := extractVideoFilter([]string{"-c:v", "libx264", "-vf", "scale=-2:1080", "-crf", "23"})
+	if filter != "scale=-2:1080" || !reflect.DeepEqual(args, []string{"-c:v", "libx264", "-crf", "23"}) {
+		t.Errorf("extractVideoFilter() = %v, %q", args, filter)
+	}
+	args, filter = extractVideoFilter([]string{"-c", "copy"})
+	if filter != "" || !reflect.DeepEqual(args, []string{"-c", "copy"}) {
+		t.Errorf("extractVideoFilter() without -vf = %v, %q", args, filter)
+	}
+}
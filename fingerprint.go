@@ -0,0 +1,480 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fingerprint tuning. Frames are sampled at the same relative positions in every file, so
+// re-encodes, rescales and watermark-free reposts of a clip hash to nearly the same values.
+const (
+	frameHashSamples        = 16   // frames hashed per video
+	maxFrameHashDistance    = 12   // average differing bits (of 64) for frames to match
+	audioWindowSeconds      = 0.5  // energy window of the audio fingerprint
+	audioFingerprintSeconds = 600  // only the first ten minutes are fingerprinted
+	audioSampleRate         = 4000 // plenty for loudness contours
+	minAudioAgreement       = 0.85 // share of matching audio bits for a match
+	maxAudioShiftWindows    = 4    // tolerated offset between the two audio tracks
+)
+
+// mediaExtensions are the files the dedupe scan fingerprints
+var mediaExtensions = map[string]bool{
+	".mp4": true, ".mkv": true, ".mov": true, ".webm": true, ".m4v": true,
+	".m4a": true, ".mp3": true, ".wav": true, ".flac": true, ".opus": true, ".ogg": true,
+}
+
+// MediaFingerprint identifies a media file by what it looks and sounds like rather than its bytes
+type MediaFingerprint struct {
+	Path          string    `json:"path"`
+	Size          int64     `json:"size"`
+	ModTime       time.Time `json:"modTime"`
+	Duration      float64   `json:"durationSeconds"`
+	FrameHashes   []string  `json:"frameHashes,omitempty"` // 64-bit difference hashes in hex
+	AudioBits     string    `json:"audioBits,omitempty"`   // packed energy-slope bits in hex
+	AudioBitCount int       `json:"audioBitCount,omitempty"`
+}
+
+// frameDHash computes a difference hash of a 9x8 grayscale frame: one bit per pixel that is
+// brighter than its right-hand neighbour
+func frameDHash(pixels []byte) uint64 {
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if pixels[y*9+x] > pixels[y*9+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// computeFrameHashes samples frames evenly over the video and hashes each one
+func computeFrameHashes(path string, duration float64) ([]string, error) {
+	rate := "1"
+	if duration > 0 {
+		rate = strconv.FormatFloat(frameHashSamples/duration, 'f', 6, 64)
+	}
+	cmd := exec.Command("ffmpeg", "-v", "error", "-i", path,
+		"-vf", "fps="+rate+",scale=9:8:flags=area,format=gray",
+		"-frames:v", strconv.Itoa(frameHashSamples), "-f", "rawvideo", "pipe:1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample frames: %v\n%s", err, stderr.String())
+	}
+
+	var hashes []string
+	for len(output) >= 72 {
+		hashes = append(hashes, fmt.Sprintf("%016x", frameDHash(output[:72])))
+		output = output[72:]
+	}
+	return hashes, nil
+}
+
+// computeAudioBits reduces the audio to one bit per window: whether it got louder than the window before
+func computeAudioBits(path string) ([]bool, error) {
+	cmd := exec.Command("ffmpeg", "-v", "error", "-i", path, "-vn", "-ac", "1",
+		"-ar", strconv.Itoa(audioSampleRate), "-t", strconv.Itoa(audioFingerprintSeconds), "-f", "s16le", "pipe:1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audio: %v\n%s", err, stderr.String())
+	}
+
+	windowSamples := int(audioSampleRate * audioWindowSeconds)
+	var energies []float64
+	for len(output) >= windowSamples*2 {
+		var sum float64
+		for i := 0; i < windowSamples; i++ {
+			sample := float64(int16(binary.LittleEndian.Uint16(output[i*2:])))
+			sum += sample * sample
+		}
+		energies = append(energies, math.Log1p(sum/float64(windowSamples)))
+		output = output[windowSamples*2:]
+	}
+
+	var result []bool
+	for i := 1; i < len(energies); i++ {
+		result = append(result, energies[i] > energies[i-1])
+	}
+	return result, nil
+}
+
+// packBits stores bits as hex, most significant bit first
+func packBits(values []bool) string {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return hex.EncodeToString(packed)
+}
+
+// unpackBits reverses packBits
+func unpackBits(encoded string, count int) []bool {
+	packed, err := hex.DecodeString(encoded)
+	if err != nil || len(packed)*8 < count {
+		return nil
+	}
+	values := make([]bool, count)
+	for i := range values {
+		values[i] = packed[i/8]&(0x80>>(i%8)) != 0
+	}
+	return values
+}
+
+// ComputeFingerprint probes a media file and fingerprints its video frames and audio
+func ComputeFingerprint(path string) (*MediaFingerprint, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ProbeMedia(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fp := &MediaFingerprint{Path: path, Size: stat.Size(), ModTime: stat.ModTime(), Duration: info.Duration}
+	if info.VideoCodec != "" {
+		if fp.FrameHashes, err = computeFrameHashes(path, info.Duration); err != nil {
+			return nil, err
+		}
+	}
+	if info.AudioCodec != "" {
+		audioBits, err := computeAudioBits(path)
+		if err != nil {
+			return nil, err
+		}
+		fp.AudioBits, fp.AudioBitCount = packBits(audioBits), len(audioBits)
+	}
+	return fp, nil
+}
+
+// framesMatch compares the frame hashes sampled at the same positions
+func framesMatch(a, b []string) bool {
+	n := min(len(a), len(b))
+	if n == 0 {
+		return false
+	}
+	total := 0
+	for i := 0; i < n; i++ {
+		x, errA := strconv.ParseUint(a[i], 16, 64)
+		y, errB := strconv.ParseUint(b[i], 16, 64)
+		if errA != nil || errB != nil {
+			return false
+		}
+		total += bits.OnesCount64(x ^ y)
+	}
+	return total/n <= maxFrameHashDistance
+}
+
+// audioMatch compares the audio bits at small offsets, since reposts are often trimmed slightly
+func audioMatch(a, b []bool) bool {
+	best := 0.0
+	for shift := -maxAudioShiftWindows; shift <= maxAudioShiftWindows; shift++ {
+		same, total := 0, 0
+		for i := range a {
+			j := i + shift
+			if j < 0 || j >= len(b) {
+				continue
+			}
+			total++
+			if a[i] == b[j] {
+				same++
+			}
+		}
+		if total > 0 {
+			best = math.Max(best, float64(same)/float64(total))
+		}
+	}
+	return best >= minAudioAgreement
+}
+
+// Matches reports whether two fingerprints are the same clip: both must have video or both be
+// audio-only, durations must agree and every stream they have must match. An audio extraction
+// is therefore never a duplicate of its source video.
+func (f *MediaFingerprint) Matches(other *MediaFingerprint) bool {
+	if (len(f.FrameHashes) > 0) != (len(other.FrameHashes) > 0) {
+		return false
+	}
+	tolerance := math.Max(2, 0.05*math.Max(f.Duration, other.Duration))
+	if math.Abs(f.Duration-other.Duration) > tolerance {
+		return false
+	}
+
+	compared := false
+	if len(f.FrameHashes) > 0 && len(other.FrameHashes) > 0 {
+		if !framesMatch(f.FrameHashes, other.FrameHashes) {
+			return false
+		}
+		compared = true
+	}
+	if f.AudioBitCount > 0 && other.AudioBitCount > 0 {
+		if !audioMatch(unpackBits(f.AudioBits, f.AudioBitCount), unpackBits(other.AudioBits, other.AudioBitCount)) {
+			return false
+		}
+		compared = true
+	}
+	return compared
+}
+
+// FingerprintIndex is the library of known fingerprints, stored as JSON keyed by file path
+type FingerprintIndex struct {
+	path    string
+	Entries map[string]*MediaFingerprint `json:"entries"`
+}
+
+// fingerprintIndexMu serializes index updates from concurrent downloads
+var fingerprintIndexMu sync.Mutex
+
+// fingerprintIndexPath returns FINGERPRINT_INDEX or ./output/fingerprints.json
+func fingerprintIndexPath() string {
+	if path := os.Getenv("FINGERPRINT_INDEX"); path != "" {
+		return path
+	}
+	return "./output/fingerprints.json"
+}
+
+// LoadFingerprintIndex reads the index, treating a missing file as empty
+func LoadFingerprintIndex(path string) (*FingerprintIndex, error) {
+	index := &FingerprintIndex{path: path, Entries: map[string]*MediaFingerprint{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("failed to read fingerprint index: %v", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse fingerprint index %s: %v", path, err)
+	}
+	if index.Entries == nil {
+		index.Entries = map[string]*MediaFingerprint{}
+	}
+	return index, nil
+}
+
+// Save writes the index back to disk
+func (idx *FingerprintIndex) Save() error {
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %v", err)
+	}
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(idx.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write fingerprint index: %v", err)
+	}
+	return nil
+}
+
+// FindMatch returns an indexed file, other than fp's own, that duplicates fp.
+// Entries whose files are gone are dropped along the way.
+func (idx *FingerprintIndex) FindMatch(fp *MediaFingerprint) *MediaFingerprint {
+	var paths []string
+	for path := range idx.Entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if path == fp.Path {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			delete(idx.Entries, path)
+			continue
+		}
+		if idx.Entries[path].Matches(fp) {
+			return idx.Entries[path]
+		}
+	}
+	return nil
+}
+
+// cachedFingerprint returns the indexed fingerprint of path if the file hasn't changed since,
+// computing and storing a fresh one otherwise
+func (idx *FingerprintIndex) cachedFingerprint(path string) (*MediaFingerprint, error) {
+	if fp, ok := idx.Entries[path]; ok {
+		if stat, err := os.Stat(path); err == nil && stat.Size() == fp.Size && stat.ModTime().Equal(fp.ModTime) {
+			return fp, nil
+		}
+	}
+	fp, err := ComputeFingerprint(path)
+	if err != nil {
+		return nil, err
+	}
+	idx.Entries[path] = fp
+	return fp, nil
+}
+
+// Duplicate policies for finished downloads
+const (
+	DuplicatesWarn = "warn" // keep the new file and say what it duplicates
+	DuplicatesSkip = "skip" // delete the new file and use the library copy
+	DuplicatesOff  = "off"  // don't fingerprint downloads
+)
+
+// handleDuplicate fingerprints a finished download, records it in the index and applies the
+// duplicate policy. It returns the path to use: the library copy when a duplicate was skipped.
+// Fingerprinting problems only produce a warning since the download itself succeeded.
+func handleDuplicate(mediaPath, policy string) string {
+	if policy == DuplicatesOff {
+		return mediaPath
+	}
+
+	fp, err := ComputeFingerprint(mediaPath)
+	if err != nil {
+		fmt.Printf("⚠️ Could not fingerprint %s: %v\n", mediaPath, err)
+		return mediaPath
+	}
+
+	fingerprintIndexMu.Lock()
+	defer fingerprintIndexMu.Unlock()
+
+	index, err := LoadFingerprintIndex(fingerprintIndexPath())
+	if err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+		return mediaPath
+	}
+
+	match := index.FindMatch(fp)
+	result := mediaPath
+	switch {
+	case match == nil:
+		index.Entries[mediaPath] = fp
+	case policy == DuplicatesSkip:
+		fmt.Printf("⏭️ %s duplicates %s, removing the new copy\n", mediaPath, mediaLabel(match.Path))
+		if err := CleanUpFiles(mediaPath, SidecarPath(mediaPath)); err != nil {
+			fmt.Printf("⚠️ %v\n", err)
+		}
+		result = match.Path
+	default:
+		fmt.Printf("⚠️ %s looks like a duplicate of %s\n", mediaPath, mediaLabel(match.Path))
+		index.Entries[mediaPath] = fp
+	}
+
+	if err := index.Save(); err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+	}
+	return result
+}
+
+// isFingerprintableMedia reports whether the dedupe scan should look at a path
+func isFingerprintableMedia(path string) bool {
+	return mediaExtensions[strings.ToLower(filepath.Ext(path))] && !strings.Contains(filepath.Base(path), "_temp_")
+}
+
+// FindDuplicates fingerprints every media file under dir, reusing the index for unchanged
+// files, and returns groups of files that are the same clip, oldest first
+func FindDuplicates(dir string) ([][]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir // partial direct downloads
+			}
+			return nil
+		}
+		if isFingerprintableMedia(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %v", dir, err)
+	}
+
+	fingerprintIndexMu.Lock()
+	defer fingerprintIndexMu.Unlock()
+
+	index, err := LoadFingerprintIndex(fingerprintIndexPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var fps []*MediaFingerprint
+	for i, path := range files {
+		fmt.Printf("🔍 [%d/%d] %s\n", i+1, len(files), path)
+		fp, err := index.cachedFingerprint(path)
+		if err != nil {
+			fmt.Printf("⚠️ Skipping %s: %v\n", path, err)
+			continue
+		}
+		fps = append(fps, fp)
+	}
+	if err := index.Save(); err != nil {
+		return nil, err
+	}
+
+	// Oldest first, so the first file of each group is the original
+	sort.Slice(fps, func(i, j int) bool { return fps[i].ModTime.Before(fps[j].ModTime) })
+
+	grouped := make([]bool, len(fps))
+	var groups [][]string
+	for i := range fps {
+		if grouped[i] {
+			continue
+		}
+		group := []string{fps[i].Path}
+		for j := i + 1; j < len(fps); j++ {
+			if !grouped[j] && fps[i].Matches(fps[j]) {
+				grouped[j] = true
+				group = append(group, fps[j].Path)
+			}
+		}
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+// Dedupe prints the duplicate groups under dir and, with remove set, deletes every copy
+// but the oldest along with its sidecar
+func Dedupe(dir string, remove bool) error {
+	groups, err := FindDuplicates(dir)
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		fmt.Println("✅ No duplicates found")
+		return nil
+	}
+
+	fmt.Printf("\n📋 %d duplicate group(s):\n", len(groups))
+	for _, group := range groups {
+		fmt.Printf("\n%s (kept)\n", mediaLabel(group[0]))
+		for _, path := range group[1:] {
+			if remove {
+				if err := CleanUpFiles(path, SidecarPath(path)); err != nil {
+					return err
+				}
+			} else {
+				fmt.Printf("  duplicate: %s\n", mediaLabel(path))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// hashes renders frame hashes the way the index stores them
+func hashes(values ...uint64) []string {
+	var encoded []string
+	for _, v := range values {
+		encoded = append(encoded, fmt.Sprintf("%016x", v))
+	}
+	return encoded
+}
+
+// audioPattern returns n pseudo-random energy-slope bits
+func audioPattern(n int, seed uint32) []bool {
+	values := make([]bool, n)
+	for i := range values {
+		seed = seed*1664525 + 1013904223
+		values[i] = seed>>31 == 1
+	}
+	return values
+}
+
+func TestFrameDHash(t *testing.T) {
+	pixels := make([]byte, 9*8)
+	if got := frameDHash(pixels); got != 0 {
+		t.Errorf("flat frame hashed to %016x, want 0", got)
+	}
+	// Brightness falling left to right sets every bit
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 9; x++ {
+			pixels[y*9+x] = byte(200 - x*10)
+		}
+	}
+	if got := frameDHash(pixels); got != ^uint64(0) {
+		t.Errorf("falling gradient hashed to %016x, want all ones", got)
+	}
+}
+
+func TestPackBits(t *testing.T) {
+	tests := []struct {
+		values []bool
+		packed string
+	}{
+		{nil, ""},
+		{[]bool{true}, "80"},
+		{[]bool{true, false, true, false, false, false, false, true}, "a1"},
+		{[]bool{false, false, false, false, false, false, false, false, true, true}, "00c0"},
+	}
+	for _, tt := range tests {
+		if got := packBits(tt.values); got != tt.packed {
+			t.Errorf("packBits(%v) = %q, want %q", tt.values, got, tt.packed)
+		}
+		if got := unpackBits(tt.packed, len(tt.values)); len(tt.values) > 0 && !reflect.DeepEqual(got, tt.values) {
+			t.Errorf("unpackBits(%q) = %v, want %v", tt.packed, got, tt.values)
+		}
+	}
+	if got := unpackBits("zz", 4); got != nil {
+		t.Errorf("unpackBits accepted invalid hex: %v", got)
+	}
+	if got := unpackBits("ff", 9); got != nil {
+		t.Errorf("unpackBits read past the packed bits: %v", got)
+	}
+}
+
+func TestFramesMatch(t *testing.T) {
+	base := []uint64{0x0123456789abcdef, 0xfedcba9876543210, 0x00ff00ff00ff00ff}
+	tests := []struct {
+		name string
+		a, b []string
+		want bool
+	}{
+		{"identical", hashes(base...), hashes(base...), true},
+		{"few bits differ", hashes(base...), hashes(base[0]^0xff, base[1]^0xf, base[2]), true},
+		{"at the limit", hashes(base[0]), hashes(base[0] ^ (1<<maxFrameHashDistance - 1)), true},
+		{"over the limit", hashes(base[0]), hashes(base[0] ^ (1<<(maxFrameHashDistance+1) - 1)), false},
+		{"unrelated", hashes(base...), hashes(^base[0], ^base[1], ^base[2]), false},
+		{"shorter list compares its length", hashes(base[:2]...), hashes(base...), true},
+		{"empty", nil, hashes(base...), false},
+		{"invalid hash", []string{"not hex"}, hashes(base[0]), false},
+	}
+	for _, tt := range tests {
+		if got := framesMatch(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: framesMatch() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAudioMatch(t *testing.T) {
+	a := audioPattern(400, 1)
+	noisy := append([]bool{}, a...)
+	for i := 0; i < len(noisy); i += 10 {
+		noisy[i] = !noisy[i] // 10% of the bits flipped
+	}
+	tests := []struct {
+		name string
+		b    []bool
+		want bool
+	}{
+		{"identical", a, true},
+		{"trimmed by two windows", a[2:], true},
+		{"delayed by three windows", append([]bool{false, true, false}, a...), true},
+		{"shifted past the tolerance", a[maxAudioShiftWindows+3:], false},
+		{"some noise", noisy, true},
+		{"different audio", audioPattern(400, 2), false},
+	}
+	for _, tt := range tests {
+		if got := audioMatch(a, tt.b); got != tt.want {
+			t.Errorf("%s: audioMatch() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMediaFingerprintMatches(t *testing.T) {
+	audio := audioPattern(200, 7)
+	video := hashes(0x0123456789abcdef, 0xfedcba9876543210)
+	clip := &MediaFingerprint{Duration: 100, FrameHashes: video, AudioBits: packBits(audio), AudioBitCount: len(audio)}
+
+	otherAudio := audioPattern(200, 8)
+	tests := []struct {
+		name  string
+		other *MediaFingerprint
+		want  bool
+	}{
+		{"same clip", &MediaFingerprint{Duration: 101, FrameHashes: video, AudioBits: packBits(audio), AudioBitCount: len(audio)}, true},
+		{"audio extracted from it", &MediaFingerprint{Duration: 100, AudioBits: packBits(audio), AudioBitCount: len(audio)}, false},
+		{"silent copy", &MediaFingerprint{Duration: 100, FrameHashes: video}, true},
+		{"durations differ", &MediaFingerprint{Duration: 120, FrameHashes: video, AudioBits: packBits(audio), AudioBitCount: len(audio)}, false},
+		{"same picture, other audio", &MediaFingerprint{Duration: 100, FrameHashes: video, AudioBits: packBits(otherAudio), AudioBitCount: len(otherAudio)}, false},
+		{"nothing to compare", &MediaFingerprint{Duration: 100}, false},
+	}
+	for _, tt := range tests {
+		if got := clip.Matches(tt.other); got != tt.want {
+			t.Errorf("%s: Matches() = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := tt.other.Matches(clip); got != tt.want {
+			t.Errorf("%s: Matches() in reverse = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	song := &MediaFingerprint{Duration: 100, AudioBits: packBits(audio), AudioBitCount: len(audio)}
+	reupload := &MediaFingerprint{Duration: 99, AudioBits: packBits(audio), AudioBitCount: len(audio)}
+	if !song.Matches(reupload) {
+		t.Error("two audio-only copies of the same recording did not match")
+	}
+}
+
+func TestFingerprintIndexFindMatch(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "original.mp4")
+	if err := os.WriteFile(original, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	video := hashes(0x0123456789abcdef)
+	idx := &FingerprintIndex{Entries: map[string]*MediaFingerprint{
+		original:                       {Path: original, Duration: 60, FrameHashes: video},
+		filepath.Join(dir, "gone.mp4"): {Path: filepath.Join(dir, "gone.mp4"), Duration: 60, FrameHashes: video},
+	}}
+
+	repost := &MediaFingerprint{Path: filepath.Join(dir, "repost.mp4"), Duration: 61, FrameHashes: video}
+	if got := idx.FindMatch(repost); got == nil || got.Path != original {
+		t.Errorf("FindMatch() = %+v, want %s", got, original)
+	}
+	if _, ok := idx.Entries[filepath.Join(dir, "gone.mp4")]; ok {
+		t.Error("FindMatch kept the entry of a deleted file")
+	}
+	if got := idx.FindMatch(idx.Entries[original]); got != nil {
+		t.Errorf("FindMatch matched a file against itself: %+v", got)
+	}
+}
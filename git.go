@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// runGit runs git with the given arguments and returns its trimmed stdout
+func runGit(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v\n%s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// DefaultBranch returns the name of the repository's default branch.
+// GIT_DEFAULT_BRANCH overrides detection; otherwise origin/HEAD is used,
+// falling back to a local main or master branch.
+func DefaultBranch() (string, error) {
+	if name := os.Getenv("GIT_DEFAULT_BRANCH"); name != "" {
+		return name, nil
+	}
+
+	if ref, err := runGit("symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, "origin/"), nil
+	}
+
+	for _, name := range []string{"main", "master"} {
+		if _, err := runGit("rev-parse", "--verify", "--quiet", "refs/heads/"+name); err == nil {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("could not determine default branch; set GIT_DEFAULT_BRANCH")
+}
+
+// CurrentBranch returns the name of the checked out branch
+func CurrentBranch() (string, error) {
+	return runGit("rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// aheadBehind counts the commits on ref that are not on base (ahead)
+// and the commits on base that are not on ref (behind)
+func aheadBehind(ref, base string) (int, int, error) {
+	output, err := runGit("rev-list", "--left-right", "--count", ref+"..."+base)
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseAheadBehind(output)
+}
+
+// parseAheadBehind reads the "<ahead>\t<behind>" output of git rev-list --left-right --count
+func parseAheadBehind(output string) (int, int, error) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rev-list output: %q", output)
+	}
+	ahead, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid ahead count %q: %v", fields[0], err)
+	}
+	behind, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid behind count %q: %v", fields[1], err)
+	}
+	return ahead, behind, nil
+}
+
+// isMergedInto reports whether every commit on ref is reachable from base
+func isMergedInto(ref, base string) bool {
+	_, err := runGit("merge-base", "--is-ancestor", ref, base)
+	return err == nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestParseAheadBehind(t *testing.T) {
+	tests := []struct {
+		output        string
+		ahead, behind int
+		wantErr       bool
+	}{
+		{"0\t0", 0, 0, false},
+		{"3\t12", 3, 12, false},
+		{"  7   1  ", 7, 1, false},
+		{"", 0, 0, true},
+		{"4", 0, 0, true},
+		{"1\t2\t3", 0, 0, true},
+		{"x\t2", 0, 0, true},
+		{"2\ty", 0, 0, true},
+	}
+	for _, tt := range tests {
+		ahead, behind, err := parseAheadBehind(tt.output)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAheadBehind(%q) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+			continue
+		}
+		if ahead != tt.ahead || behind != tt.behind {
+			t.Errorf("parseAheadBehind(%q) = %d, %d, want %d, %d", tt.output, ahead, behind, tt.ahead, tt.behind)
+		}
+	}
+}
@@ -12,7 +12,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/google/uuid"
 	"github.com/skip2/go-qrcode"
 )
 
@@ -21,8 +20,23 @@ func ShowHelp() {
 	fmt.Println("\nCommands:")
 	fmt.Println("  copy-branch                            Simulate copying current Git branch")
 	fmt.Println("  delete-all-branches branch1 branch2    Simulate deleting all local branches except specified")
-	fmt.Println("  transcribe <URL|PATH>                  Download video from URL and extract text")
-	fmt.Println("  split-video                            Split video into clips based on audio")
+	fmt.Println("  branches [-sort] [-match] [-json]      Show upstream, ahead/behind and merge status of local branches")
+	fmt.Println("  commit-msg [-install-hook]             Generate a conventional commit message for staged changes")
+	fmt.Println("  pr-description [-o file] [-template]   Generate a Markdown pull request description for the current branch")
+	fmt.Println("  changelog [-from] [-to] [-prepend]     Group commits by conventional commit type into Markdown release notes")
+	fmt.Println("  sync-branches [-strategy] [-match]     Fast-forward the default branch and rebase or merge local branches onto it")
+	fmt.Println("  transcribe [-backend] [-model] -video  Transcribe a video with Whisper, whisper.cpp or an OpenAI-compatible API")
+	fmt.Println("  transcribe -from <srt|vtt|json> -video Use an existing transcript, saved as text, SRT, WebVTT and JSON")
+	fmt.Println("  edit [-export] [-no-editor] -video     Cut a video by deleting words from its transcript in $EDITOR")
+	fmt.Println("  transcript-cache [-invalidate] [file]  List cached transcripts by audio content, or drop them to transcribe again")
+	fmt.Println("  download [-queue] [-preset] <URL>      Download from YouTube, X, BlueSky, Reddit or direct links, optionally many at once")
+	fmt.Println("  download -playlist|-channel <URL>      Download new videos of a playlist or channel, filtered by date, title, duration or count")
+	fmt.Println("  download -audio [-audio-format] <URL>  Download only the audio as wav, mp3, m4a or flac, optionally loudness-normalized")
+	fmt.Println("  record [-segment] [-duration] <URL>    Record a livestream in rolling segments and stitch them when it ends")
+	fmt.Println("  monitor [-once] [-interval] [-config]  Watch YouTube channels, X accounts and BlueSky feeds and download new videos")
+	fmt.Println("  dedupe [-dir] [-remove]                Find downloads that are the same clip by frame and audio fingerprints")
+	fmt.Println("  cookies                                Check the configured cookie files for missing or expired logins")
+	fmt.Println("  split-video [-preset] <video-file>     Split video into clips based on audio")
 }
 
 func generateQRCodeConsole(text string) error {
@@ -72,79 +86,70 @@ func CleanUpFiles(files ...string) error {
 	return nil
 }
 
-// DownloadVideo uses yt-dlp to download the video from a URL and extract audio with ffmpeg
-func DownloadVideo(videoURL string) error {
-	// Ensure the "output" directory exists
-	EnsureOutputDir("output")
+// DownloadOptions holds the settings shared by the downloaders
+type DownloadOptions struct {
+	NameTemplate string          // output name template; empty uses the provider default
+	Preset       *EncodingPreset // encoding preset; nil uses the downloader's default
+	TimeRange    *TimeRange      // only download this section; nil downloads everything
+	CaptionLang  string          // also fetch captions in this language; empty skips them
+	Connections  int             // parallel connections for direct and HLS downloads; 0 uses the default
+	Audio        *AudioOptions   // download only the audio in this format; nil downloads the video
+	Duplicates   string          // DuplicatesWarn, DuplicatesSkip or DuplicatesOff; empty warns
+}
 
-	// Download the audio-only m4a format
-	cmd := exec.Command("yt-dlp", "-f", "140", "-o", "./output/audio.m4a", videoURL)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error downloading video: %v", err)
+// downloadCaptionsFor fetches captions for a finished download when they were requested.
+// Missing captions only produce a warning since the video itself succeeded.
+func (opts DownloadOptions) downloadCaptionsFor(videoURL, outputFile string) {
+	if opts.CaptionLang == "" {
+		return
 	}
-
-	// Remove audio.wav if it exists
-	if _, err := os.Stat("./output/audio.wav"); err == nil {
-		if err := os.Remove("./output/audio.wav"); err != nil {
-			return fmt.Errorf("failed to delete existing audio.wav: %v", err)
+	if _, err := DownloadCaptions(videoURL, opts.CaptionLang, captionNameFor(outputFile), opts.TimeRange); err != nil {
+		fmt.Printf("⚠️ Could not save captions: %v\n", err)
+		return
+	}
+	// Cache the captions by the video's audio so other copies of it use them too
+	if transcript, _, err := LoadTranscript(captionNameFor(outputFile)); err == nil && transcript != nil {
+		if err := cacheTranscriptFor(outputFile, transcript); err != nil {
+			fmt.Printf("⚠️ Could not cache captions: %v\n", err)
 		}
 	}
+}
 
-	// Convert the m4a audio to wav format
-	cmd = exec.Command("ffmpeg", "-i", "./output/audio.m4a", "./output/audio.wav")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error converting audio: %v", err)
+// nameSuffix returns the text appended to output names for these options
+func (opts DownloadOptions) nameSuffix() string {
+	if opts.TimeRange == nil {
+		return ""
 	}
-
-	return nil
+	return opts.TimeRange.NameSuffix()
 }
 
-// DownloadVideoAsMP4 downloads and re-encodes a video to H.264 for Premiere Pro compatibility
-func DownloadVideoAsMP4(videoURL string) error {
-	tempVideoFile := fmt.Sprintf("./output/%s_temp_video.mp4", uuid.New().String())
-	outputFile := fmt.Sprintf("./output/%s_video.mp4", uuid.New().String())
-
-	// Download the best video and audio, merged into a single file
-	cmd := exec.Command("yt-dlp", "-f", "bestvideo[ext=mp4]+bestaudio[ext=m4a]", "-o", tempVideoFile, "-N", "16", videoURL)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error downloading video: %v", err)
-	}
+// runYTDLP downloads videoURL in the given format to outputFile. With a time range it asks
+// yt-dlp to fetch only that section; if that fails the whole video is downloaded instead.
+// It reports whether the file was already cut to the range.
+func runYTDLP(videoURL, format, outputFile string, timeRange *TimeRange, extraArgs ...string) (bool, error) {
+	args := append([]string{"-f", format, "-o", outputFile}, extraArgs...)
+	args = append(args, ytDLPAuthArgs(videoURL)...)
 
-	// Check if the merged file exists
-	if _, err := os.Stat(tempVideoFile); err != nil {
-		return fmt.Errorf("merged video file not found: %v", err)
+	if timeRange != nil {
+		fmt.Printf("✂️ Downloading section %s\n", timeRange.YTDLPSection())
+		cmd := exec.Command("yt-dlp", append(args, "--download-sections", timeRange.YTDLPSection(), videoURL)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+		if err == nil {
+			return true, nil
+		}
+		fmt.Printf("⚠️ Section download failed (%v), downloading the full video and trimming instead...\n", err)
+		CleanUpFiles(outputFile, outputFile+".part")
 	}
 
-	// Re-encode the video to H.264 for Premiere Pro compatibility
-	cmd = exec.Command(
-		"ffmpeg",
-		"-i", tempVideoFile,
-		"-c:v", "libx264",
-		"-preset", "slow",
-		"-crf", "23",
-		"-c:a", "aac",
-		"-b:a", "128k",
-		outputFile,
-	)
+	cmd := exec.Command("yt-dlp", append(args, videoURL)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error re-encoding video: %v", err)
+		return false, err
 	}
-
-	// Clean up temporary files
-	if err := CleanUpFiles(tempVideoFile); err != nil {
-		return fmt.Errorf("error cleaning up temporary files: %v", err)
-	}
-
-	fmt.Printf("Video downloaded and saved as %s\n", outputFile)
-	return nil
+	return false, nil
 }
 
 // ExtractAudio uses ffmpeg to extract audio from a video file
@@ -158,7 +163,8 @@ func ExtractAudio(videoFile, audioFile string) error {
 
 // GetVideoTitle fetches the title of the video using yt-dlp
 func GetVideoTitle(videoURL string) (string, error) {
-	cmd := exec.Command("yt-dlp", "--get-title", videoURL)
+	args := append([]string{"--get-title"}, ytDLPAuthArgs(videoURL)...)
+	cmd := exec.Command("yt-dlp", append(args, videoURL)...)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("error fetching video title: %v", err)
@@ -363,7 +369,9 @@ const minClipDurationMs = 50   // Minimum allowed duration for clips in millisec
 const startBufferSeconds = 1.5 // Buffer to adjust talking start time earlier
 const endBufferSeconds = 1.5   // Buffer to adjust talking end time later
 
-func SplitVideo(videoFile string, threshold float64, duration float64) error {
+// SplitVideo cuts the talking parts of a video into clips. With a nil preset the clips are
+// stream copies; with a preset they are re-encoded, which also makes the cuts frame-accurate.
+func SplitVideo(videoFile string, threshold float64, duration float64, preset *EncodingPreset) error {
 	outputDir := fmt.Sprintf("output/%s", strings.TrimSuffix(filepath.Base(videoFile), filepath.Ext(videoFile)))
 	os.MkdirAll(outputDir, os.ModePerm)
 
@@ -418,17 +426,23 @@ func SplitVideo(videoFile string, threshold float64, duration float64) error {
 			continue
 		}
 
-		outputClip := fmt.Sprintf("%s/clip_%d.mp4", outputDir, i+1)
+		clipExt := "mp4"
+		codecArgs := []string{"-c", "copy"}
+		if preset != nil {
+			clipExt = preset.Container
+			codecArgs = preset.CodecArgs(TranscodeFull)
+		}
+		outputClip := fmt.Sprintf("%s/clip_%d.%s", outputDir, i+1, clipExt)
 
 		log.Printf("Creating clip %d: Start=%.2f (Buffered), End=%.2f (Buffered)", i+1, start, end)
-		splitCmd := exec.Command("ffmpeg",
+		splitArgs := []string{
 			"-y",
 			"-i", videoFile,
 			"-ss", fmt.Sprintf("%.2f", start),
 			"-to", fmt.Sprintf("%.2f", end),
-			"-c", "copy",
-			outputClip,
-		)
+		}
+		splitArgs = append(splitArgs, codecArgs...)
+		splitCmd := exec.Command("ffmpeg", append(splitArgs, outputClip)...)
 
 		splitOutput, splitErr := splitCmd.CombinedOutput()
 		log.Printf("FFmpeg output for clip %d:\n%s", i+1, string(splitOutput))
@@ -442,25 +456,3 @@ func SplitVideo(videoFile string, threshold float64, duration float64) error {
 	log.Println("Splitting complete.")
 	return nil
 }
-
-// DownloadFromX downloads a video from an X.com (Twitter) post
-func DownloadFromX(postURL string) error {
-	// Ensure the "output" directory exists
-	EnsureOutputDir("output")
-
-	// Generate a unique filename for the output video
-	outputFile := fmt.Sprintf("./output/%s_x_video.mp4", uuid.New().String())
-
-	// Use yt-dlp to fetch the video from the provided X.com post URL
-	cmd := exec.Command("yt-dlp", "-f", "best", "-o", outputFile, postURL)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	fmt.Printf("Downloading video from X.com: %s\n", postURL)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error downloading video from X.com: %v", err)
-	}
-
-	fmt.Printf("Video downloaded and saved as %s\n", outputFile)
-	return nil
-}
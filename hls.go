@@ -0,0 +1,515 @@
+package main
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HLSVariant is one stream listed in a master playlist
+type HLSVariant struct {
+	URI        string
+	Bandwidth  int
+	Width      int
+	Height     int
+	Codecs     string
+	AudioGroup string
+}
+
+// HLSRendition is an alternative audio stream from an #EXT-X-MEDIA tag
+type HLSRendition struct {
+	Type    string
+	GroupID string
+	Name    string
+	URI     string
+	Default bool
+}
+
+// HLSKey describes how the following segments are encrypted
+type HLSKey struct {
+	Method string // NONE or AES-128
+	URI    string
+	IV     []byte // nil means the segment's media sequence number
+}
+
+// HLSSegment is one media segment of a media playlist
+type HLSSegment struct {
+	URI      string
+	Duration float64
+	Sequence int
+	Length   int64 // byte range length; 0 fetches the whole resource
+	Offset   int64
+	Key      *HLSKey
+}
+
+// HLSPlaylist is a parsed master or media playlist. URIs are resolved against the playlist URL.
+type HLSPlaylist struct {
+	Variants   []HLSVariant
+	Renditions []HLSRendition
+	Segments   []HLSSegment
+	InitURI    string // #EXT-X-MAP initialization section of fMP4 streams
+	Ended      bool   // #EXT-X-ENDLIST was present, so the playlist is not live
+}
+
+// IsMaster reports whether the playlist lists variants rather than segments
+func (p *HLSPlaylist) IsMaster() bool {
+	return len(p.Variants) > 0
+}
+
+// isHLSURL reports whether the URL points at an m3u8 playlist
+func isHLSURL(videoURL string) bool {
+	parsed, err := url.Parse(videoURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(filepath.Ext(parsed.Path), ".m3u8")
+}
+
+// parseHLSAttributes splits an attribute list like BANDWIDTH=1280000,CODECS="avc1,mp4a"
+func parseHLSAttributes(list string) map[string]string {
+	attrs := map[string]string{}
+	for list != "" {
+		key, rest, found := strings.Cut(list, "=")
+		if !found {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+			rest = strings.TrimPrefix(rest, ",")
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		attrs[strings.TrimSpace(key)] = value
+		list = rest
+	}
+	return attrs
+}
+
+// resolveHLSURI makes a playlist URI absolute
+func resolveHLSURI(base *url.URL, uri string) (string, error) {
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid playlist URI %q: %v", uri, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// ParseHLSPlaylist parses a master or media playlist fetched from playlistURL
+func ParseHLSPlaylist(r io.Reader, playlistURL string) (*HLSPlaylist, error) {
+	base, err := url.Parse(playlistURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid playlist URL: %v", err)
+	}
+
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff")) != "#EXTM3U" {
+		return nil, fmt.Errorf("not an HLS playlist: %s", playlistURL)
+	}
+
+	playlist := &HLSPlaylist{}
+	var pendingVariant *HLSVariant
+	var duration float64
+	var length, offset, nextOffset int64
+	var key *HLSKey
+	sequence := 0
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		tag, value, _ := strings.Cut(line, ":")
+
+		switch {
+		case tag == "#EXT-X-STREAM-INF":
+			attrs := parseHLSAttributes(value)
+			variant := HLSVariant{Codecs: attrs["CODECS"], AudioGroup: attrs["AUDIO"]}
+			variant.Bandwidth, _ = strconv.Atoi(attrs["BANDWIDTH"])
+			if w, h, found := strings.Cut(attrs["RESOLUTION"], "x"); found {
+				variant.Width, _ = strconv.Atoi(w)
+				variant.Height, _ = strconv.Atoi(h)
+			}
+			pendingVariant = &variant
+		case tag == "#EXT-X-MEDIA":
+			attrs := parseHLSAttributes(value)
+			rendition := HLSRendition{Type: attrs["TYPE"], GroupID: attrs["GROUP-ID"], Name: attrs["NAME"], Default: attrs["DEFAULT"] == "YES"}
+			if attrs["URI"] != "" {
+				if rendition.URI, err = resolveHLSURI(base, attrs["URI"]); err != nil {
+					return nil, err
+				}
+			}
+			playlist.Renditions = append(playlist.Renditions, rendition)
+		case tag == "#EXT-X-MEDIA-SEQUENCE":
+			sequence, _ = strconv.Atoi(value)
+		case tag == "#EXTINF":
+			durationText, _, _ := strings.Cut(value, ",")
+			duration, _ = strconv.ParseFloat(durationText, 64)
+		case tag == "#EXT-X-BYTERANGE":
+			lengthText, offsetText, hasOffset := strings.Cut(value, "@")
+			length, _ = strconv.ParseInt(lengthText, 10, 64)
+			offset = nextOffset
+			if hasOffset {
+				offset, _ = strconv.ParseInt(offsetText, 10, 64)
+			}
+		case tag == "#EXT-X-KEY":
+			attrs := parseHLSAttributes(value)
+			key = &HLSKey{Method: attrs["METHOD"]}
+			if key.Method == "NONE" {
+				key = nil
+				break
+			}
+			if key.Method != "AES-128" {
+				return nil, fmt.Errorf("unsupported HLS encryption %s", key.Method)
+			}
+			if key.URI, err = resolveHLSURI(base, attrs["URI"]); err != nil {
+				return nil, err
+			}
+			if iv := attrs["IV"]; iv != "" {
+				if key.IV, err = hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(iv, "0x"), "0X")); err != nil || len(key.IV) != aes.BlockSize {
+					return nil, fmt.Errorf("invalid HLS key IV %q", iv)
+				}
+			}
+		case tag == "#EXT-X-MAP":
+			attrs := parseHLSAttributes(value)
+			if attrs["BYTERANGE"] != "" {
+				return nil, fmt.Errorf("byte-range initialization sections are not supported")
+			}
+			if playlist.InitURI, err = resolveHLSURI(base, attrs["URI"]); err != nil {
+				return nil, err
+			}
+		case tag == "#EXT-X-ENDLIST":
+			playlist.Ended = true
+		case strings.HasPrefix(line, "#"):
+			// other tags and comments don't affect the download
+		case pendingVariant != nil:
+			if pendingVariant.URI, err = resolveHLSURI(base, line); err != nil {
+				return nil, err
+			}
+			playlist.Variants = append(playlist.Variants, *pendingVariant)
+			pendingVariant = nil
+		default:
+			uri, err := resolveHLSURI(base, line)
+			if err != nil {
+				return nil, err
+			}
+			playlist.Segments = append(playlist.Segments, HLSSegment{URI: uri, Duration: duration, Sequence: sequence, Length: length, Offset: offset, Key: key})
+			sequence++
+			nextOffset = offset + length
+			duration, length, offset = 0, 0, 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read playlist: %v", err)
+	}
+	return playlist, nil
+}
+
+// SelectHLSVariant picks the highest-bandwidth variant that is no taller than maxHeight
+// (0 for no limit), or the smallest one if every variant is taller
+func SelectHLSVariant(variants []HLSVariant, maxHeight int) HLSVariant {
+	sorted := append([]HLSVariant{}, variants...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Bandwidth > sorted[j].Bandwidth })
+
+	for _, v := range sorted {
+		if maxHeight == 0 || v.Height == 0 || v.Height <= maxHeight {
+			return v
+		}
+	}
+	return sorted[len(sorted)-1]
+}
+
+// audioRenditionFor returns the audio stream to pair with a variant, or nil when its audio is muxed in
+func audioRenditionFor(playlist *HLSPlaylist, variant HLSVariant) *HLSRendition {
+	if variant.AudioGroup == "" {
+		return nil
+	}
+	var found *HLSRendition
+	for i, r := range playlist.Renditions {
+		if r.Type != "AUDIO" || r.GroupID != variant.AudioGroup || r.URI == "" {
+			continue
+		}
+		if found == nil || r.Default {
+			found = &playlist.Renditions[i]
+		}
+	}
+	return found
+}
+
+// fetchHLSBytes GETs a URL, optionally limited to a byte range
+func fetchHLSBytes(client *http.Client, uri string, length, offset int64) ([]byte, error) {
+	start, end := int64(0), int64(-1)
+	if length > 0 {
+		start, end = offset, offset+length-1
+	}
+	req, err := newHTTPRequest(uri, start, end)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("HTTP %d %s for %s", resp.StatusCode, http.StatusText(resp.StatusCode), uri)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// FetchHLSPlaylist downloads and parses a playlist
+func FetchHLSPlaylist(client *http.Client, playlistURL string) (*HLSPlaylist, error) {
+	data, err := fetchHLSBytes(client, playlistURL, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch playlist: %v", err)
+	}
+	return ParseHLSPlaylist(strings.NewReader(string(data)), playlistURL)
+}
+
+// hlsKeyCache fetches each encryption key once
+type hlsKeyCache struct {
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+func (c *hlsKeyCache) get(client *http.Client, uri string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.keys[uri]; ok {
+		return key, nil
+	}
+	key, err := fetchHLSBytes(client, uri, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch HLS key: %v", err)
+	}
+	if len(key) != aes.BlockSize {
+		return nil, fmt.Errorf("HLS key has %d bytes, expected %d", len(key), aes.BlockSize)
+	}
+	c.keys[uri] = key
+	return key, nil
+}
+
+// decryptHLSSegment undoes AES-128 CBC encryption and its PKCS#7 padding
+func decryptHLSSegment(data, key []byte, segment HLSSegment) ([]byte, error) {
+	if len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted segment size %d is not a multiple of the block size", len(data))
+	}
+	iv := segment.Key.IV
+	if iv == nil {
+		iv = make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint64(iv[8:], uint64(segment.Sequence))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, data)
+
+	if len(data) > 0 {
+		padding := int(data[len(data)-1])
+		if padding > 0 && padding <= aes.BlockSize && padding <= len(data) {
+			data = data[:len(data)-padding]
+		}
+	}
+	return data, nil
+}
+
+// downloadHLSSegments fetches every segment of a media playlist into dir with a worker pool.
+// Segments already on disk from an earlier run are kept, so an interrupted download resumes.
+// It returns the segment files in playback order, preceded by the init section if there is one.
+func downloadHLSSegments(client *http.Client, playlist *HLSPlaylist, dir string, workers int) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create segment directory: %v", err)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	var files []string
+	if playlist.InitURI != "" {
+		initFile := filepath.Join(dir, "init.mp4")
+		if _, err := os.Stat(initFile); err != nil {
+			data, err := fetchHLSBytes(client, playlist.InitURI, 0, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch init section: %v", err)
+			}
+			if err := os.WriteFile(initFile, data, 0644); err != nil {
+				return nil, err
+			}
+		}
+		files = append(files, initFile)
+	}
+
+	keys := &hlsKeyCache{keys: map[string][]byte{}}
+	segmentFiles := make([]string, len(playlist.Segments))
+	errs := make([]error, len(playlist.Segments))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var doneMu sync.Mutex
+	done := 0
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				segmentFiles[i] = filepath.Join(dir, fmt.Sprintf("segment_%05d.ts", i))
+				errs[i] = downloadHLSSegment(client, keys, playlist.Segments[i], segmentFiles[i])
+
+				doneMu.Lock()
+				done++
+				if done%25 == 0 || done == len(playlist.Segments) {
+					fmt.Printf("⬇️ %d/%d segments\n", done, len(playlist.Segments))
+				}
+				doneMu.Unlock()
+			}
+		}()
+	}
+	for i := range playlist.Segments {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("segment %d: %v", i, err)
+		}
+	}
+	return append(files, segmentFiles...), nil
+}
+
+// downloadHLSSegment saves one segment, retrying a few times before giving up
+func downloadHLSSegment(client *http.Client, keys *hlsKeyCache, segment HLSSegment, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return nil
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= 3; attempt++ {
+		lastErr = func() error {
+			data, err := fetchHLSBytes(client, segment.URI, segment.Length, segment.Offset)
+			if err != nil {
+				return err
+			}
+			if segment.Key != nil {
+				key, err := keys.get(client, segment.Key.URI)
+				if err != nil {
+					return err
+				}
+				if data, err = decryptHLSSegment(data, key, segment); err != nil {
+					return err
+				}
+			}
+			// Write under a temporary name so a crash never leaves a truncated segment behind
+			if err := os.WriteFile(dest+".part", data, 0644); err != nil {
+				return err
+			}
+			return os.Rename(dest+".part", dest)
+		}()
+		if lastErr == nil {
+			return nil
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+	return lastErr
+}
+
+// concatFiles joins files byte for byte, which is valid for MPEG-TS and for fMP4 with its init section
+func concatFiles(files []string, dest string) error {
+	out, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", dest, err)
+	}
+	defer out.Close()
+
+	for _, name := range files {
+		in, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, in)
+		in.Close()
+		if err != nil {
+			return fmt.Errorf("failed to join segments: %v", err)
+		}
+	}
+	return out.Close()
+}
+
+// downloadHLSStream fetches all segments of a media playlist into one file
+func downloadHLSStream(client *http.Client, playlistURL, workDir, dest string, workers int) error {
+	playlist, err := FetchHLSPlaylist(client, playlistURL)
+	if err != nil {
+		return err
+	}
+	if len(playlist.Segments) == 0 {
+		return fmt.Errorf("playlist %s has no segments", playlistURL)
+	}
+	if !playlist.Ended {
+		fmt.Println("⚠️ Playlist is live, downloading the segments available now (use record for live streams)")
+	}
+
+	files, err := downloadHLSSegments(client, playlist, workDir, workers)
+	if err != nil {
+		return err
+	}
+	return concatFiles(files, dest)
+}
+
+// DownloadHLS downloads an HLS stream to dest (a container ffmpeg can write, e.g. .mkv or .mp4).
+// For a master playlist the best variant no taller than maxHeight is used, along with its
+// separate audio rendition if it has one. Segments are kept in workDir until the remux succeeds,
+// so rerunning an interrupted download only fetches the missing ones.
+func DownloadHLS(playlistURL, workDir, dest string, maxHeight, workers int) error {
+	client := &http.Client{}
+
+	playlist, err := FetchHLSPlaylist(client, playlistURL)
+	if err != nil {
+		return err
+	}
+
+	videoURL, audioURL := playlistURL, ""
+	if playlist.IsMaster() {
+		variant := SelectHLSVariant(playlist.Variants, maxHeight)
+		fmt.Printf("📺 Selected HLS variant: %dx%d, %d kbps %s\n", variant.Width, variant.Height, variant.Bandwidth/1000, variant.Codecs)
+		videoURL = variant.URI
+		if audio := audioRenditionFor(playlist, variant); audio != nil {
+			fmt.Printf("🔊 Selected audio rendition: %s\n", audio.Name)
+			audioURL = audio.URI
+		}
+	}
+
+	videoFile := filepath.Join(workDir, "video.stream")
+	if err := downloadHLSStream(client, videoURL, filepath.Join(workDir, "video"), videoFile, workers); err != nil {
+		return err
+	}
+
+	inputs := []string{videoFile}
+	if audioURL != "" {
+		audioFile := filepath.Join(workDir, "audio.stream")
+		if err := downloadHLSStream(client, audioURL, filepath.Join(workDir, "audio"), audioFile, workers); err != nil {
+			return err
+		}
+		inputs = append(inputs, audioFile)
+	}
+	return remuxStreams(inputs, dest)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHLSAttributes(t *testing.T) {
+	tests := []struct {
+		list string
+		want map[string]string
+	}{
+		{"", map[string]string{}},
+		{"BANDWIDTH=1280000", map[string]string{"BANDWIDTH": "1280000"}},
+		{
+			`BANDWIDTH=1280000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=1280x720`,
+			map[string]string{"BANDWIDTH": "1280000", "CODECS": "avc1.4d401f,mp4a.40.2", "RESOLUTION": "1280x720"},
+		},
+		{
+			`TYPE=AUDIO,GROUP-ID="aud",NAME="English",DEFAULT=YES,URI="audio/en.m3u8"`,
+			map[string]string{"TYPE": "AUDIO", "GROUP-ID": "aud", "NAME": "English", "DEFAULT": "YES", "URI": "audio/en.m3u8"},
+		},
+		{`METHOD=AES-128,URI="unterminated`, map[string]string{"METHOD": "AES-128", "URI": "unterminated"}},
+		{"NOVALUE", map[string]string{}},
+	}
+	for _, tt := range tests {
+		if got := parseHLSAttributes(tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseHLSAttributes(%q) = %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestParseHLSMasterPlaylist(t *testing.T) {
+	content := "\ufeff#EXTM3U\n" +
+		`#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="English",DEFAULT=YES,URI="audio/en.m3u8"` + "\n" +
+		`#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="German",URI="https://cdn.example.com/de.m3u8"` + "\n" +
+		`#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360,CODECS="avc1.4d401e,mp4a.40.2",AUDIO="aud"` + "\n" +
+		"low/index.m3u8\n" +
+		"\n" +
+		`#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080` + "\n" +
+		"/abs/high.m3u8\n"
+
+	playlist, err := ParseHLSPlaylist(strings.NewReader(content), "https://example.com/live/master.m3u8")
+	if err != nil {
+		t.Fatalf("ParseHLSPlaylist returned error: %v", err)
+	}
+	if !playlist.IsMaster() {
+		t.Fatal("master playlist not recognized")
+	}
+	wantVariants := []HLSVariant{
+		{URI: "https://example.com/live/low/index.m3u8", Bandwidth: 800000, Width: 640, Height: 360, Codecs: "avc1.4d401e,mp4a.40.2", AudioGroup: "aud"},
+		{URI: "https://example.com/abs/high.m3u8", Bandwidth: 5000000, Width: 1920, Height: 1080},
+	}
+	if !reflect.DeepEqual(playlist.Variants, wantVariants) {
+		t.Errorf("variants = %+v, want %+v", playlist.Variants, wantVariants)
+	}
+	wantRenditions := []HLSRendition{
+		{Type: "AUDIO", GroupID: "aud", Name: "English", URI: "https://example.com/live/audio/en.m3u8", Default: true},
+		{Type: "AUDIO", GroupID: "aud", Name: "German", URI: "https://cdn.example.com/de.m3u8"},
+	}
+	if !reflect.DeepEqual(playlist.Renditions, wantRenditions) {
+		t.Errorf("renditions = %+v, want %+v", playlist.Renditions, wantRenditions)
+	}
+
+	if got := audioRenditionFor(playlist, playlist.Variants[0]); got == nil || got.Name != "English" {
+		t.Errorf("audioRenditionFor picked %+v, want the default English rendition", got)
+	}
+	if got := audioRenditionFor(playlist, playlist.Variants[1]); got != nil {
+		t.Errorf("audioRenditionFor returned %+v for a variant with muxed audio", got)
+	}
+}
+
+func TestParseHLSMediaPlaylist(t *testing.T) {
+	content := "#EXTM3U\n" +
+		"#EXT-X-VERSION:7\n" +
+		"#EXT-X-MEDIA-SEQUENCE:10\n" +
+		`#EXT-X-MAP:URI="init.mp4"` + "\n" +
+		"#EXTINF:4.0,\n" +
+		"#EXT-X-BYTERANGE:1000@0\n" +
+		"media.mp4\n" +
+		"#EXTINF:4.5,title\n" +
+		"#EXT-X-BYTERANGE:2000\n" +
+		"media.mp4\n" +
+		`#EXT-X-KEY:METHOD=AES-128,URI="key.bin",IV=0x000102030405060708090a0b0c0d0e0f` + "\n" +
+		"#EXTINF:3.25,\n" +
+		"seg3.m4s\n" +
+		"#EXT-X-KEY:METHOD=NONE\n" +
+		"#EXTINF:1,\n" +
+		"seg4.m4s\n" +
+		"#EXT-X-ENDLIST\n"
+
+	playlist, err := ParseHLSPlaylist(strings.NewReader(content), "https://example.com/vod/index.m3u8")
+	if err != nil {
+		t.Fatalf("ParseHLSPlaylist returned error: %v", err)
+	}
+	if playlist.IsMaster() || !playlist.Ended {
+		t.Errorf("IsMaster = %v, Ended = %v, want a finished media playlist", playlist.IsMaster(), playlist.Ended)
+	}
+	if playlist.InitURI != "https://example.com/vod/init.mp4" {
+		t.Errorf("InitURI = %q", playlist.InitURI)
+	}
+
+	key := &HLSKey{Method: "AES-128", URI: "https://example.com/vod/key.bin", IV: []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}}
+	want := []HLSSegment{
+		{URI: "https://example.com/vod/media.mp4", Duration: 4, Sequence: 10, Length: 1000, Offset: 0},
+		{URI: "https://example.com/vod/media.mp4", Duration: 4.5, Sequence: 11, Length: 2000, Offset: 1000},
+		{URI: "https://example.com/vod/seg3.m4s", Duration: 3.25, Sequence: 12, Key: key},
+		{URI: "https://example.com/vod/seg4.m4s", Duration: 1, Sequence: 13},
+	}
+	if !reflect.DeepEqual(playlist.Segments, want) {
+		t.Errorf("segments = %+v, want %+v", playlist.Segments, want)
+	}
+}
+
+func TestParseHLSPlaylistErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"missing header", "#EXTINF:4,\nseg.ts\n"},
+		{"empty", ""},
+		{"unsupported encryption", "#EXTM3U\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"k\"\n"},
+		{"short IV", "#EXTM3U\n#EXT-X-KEY:METHOD=AES-128,URI=\"k\",IV=0x0102\n"},
+		{"byte-range init", "#EXTM3U\n#EXT-X-MAP:URI=\"init.mp4\",BYTERANGE=\"100@0\"\n"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseHLSPlaylist(strings.NewReader(tt.content), "https://example.com/a.m3u8"); err == nil {
+			t.Errorf("%s: ParseHLSPlaylist returned no error", tt.name)
+		}
+	}
+}
+
+func TestSelectHLSVariant(t *testing.T) {
+	variants := []HLSVariant{
+		{URI: "360", Bandwidth: 800000, Height: 360},
+		{URI: "1080", Bandwidth: 5000000, Height: 1080},
+		{URI: "720", Bandwidth: 2500000, Height: 720},
+	}
+	tests := []struct {
+		maxHeight int
+		want      string
+	}{
+		{0, "1080"},
+		{720, "720"},
+		{719, "360"},
+		{100, "360"},
+	}
+	for _, tt := range tests {
+		if got := SelectHLSVariant(variants, tt.maxHeight); got.URI != tt.want {
+			t.Errorf("SelectHLSVariant(maxHeight %d) = %s, want %s", tt.maxHeight, got.URI, tt.want)
+		}
+	}
+}
+
+func TestDecryptHLSSegment(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, aes.BlockSize)
+	plain := []byte("segment payload that is not block aligned")
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	padded := append(append([]byte{}, plain...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	// Without an IV the media sequence number is used
+	iv := make([]byte, aes.BlockSize)
+	iv[15] = 42
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+
+	got, err := decryptHLSSegment(encrypted, key, HLSSegment{Sequence: 42, Key: &HLSKey{Method: "AES-128"}})
+	if err != nil {
+		t.Fatalf("decryptHLSSegment returned error: %v", err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("decryptHLSSegment() = %q, want %q", got, plain)
+	}
+
+	if _, err := decryptHLSSegment([]byte("short"), key, HLSSegment{Key: &HLSKey{Method: "AES-128"}}); err == nil {
+		t.Error("decryptHLSSegment accepted data that is not block aligned")
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minPartSize keeps small files from being split into many tiny range requests
+const minPartSize = 4 << 20
+
+// httpPart is one byte range of a parallel download. Written counts the bytes already saved,
+// so an interrupted part resumes at Start+Written.
+type httpPart struct {
+	Start   int64 `json:"start"`
+	End     int64 `json:"end"` // inclusive
+	Written int64 `json:"written"`
+}
+
+// httpDownloadState is stored next to the .part file so a rerun can resume the download
+type httpDownloadState struct {
+	URL   string     `json:"url"`
+	Size  int64      `json:"size"`
+	Parts []httpPart `json:"parts"`
+
+	mu   sync.Mutex
+	path string
+}
+
+// save writes the state file; callers hold s.mu
+func (s *httpDownloadState) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// loadHTTPDownloadState returns the saved state for dest if it belongs to the same URL and size
+// and the part file it describes is still there at full length
+func loadHTTPDownloadState(statePath, partFile, fileURL string, size int64) *httpDownloadState {
+	if info, err := os.Stat(partFile); err != nil || info.Size() < size {
+		return nil
+	}
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil
+	}
+	var state httpDownloadState
+	if err := json.Unmarshal(data, &state); err != nil || state.URL != fileURL || state.Size != size {
+		return nil
+	}
+	state.path = statePath
+	return &state
+}
+
+// newHTTPRequest builds a GET request with an optional byte range (end < 0 means open-ended)
+func newHTTPRequest(fileURL string, start, end int64) (*http.Request, error) {
+	req, err := http.NewRequest("GET", fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+	if start > 0 || end >= 0 {
+		rangeHeader := fmt.Sprintf("bytes=%d-", start)
+		if end >= 0 {
+			rangeHeader += strconv.FormatInt(end, 10)
+		}
+		req.Header.Set("Range", rangeHeader)
+	}
+	return req, nil
+}
+
+// probeHTTPSize asks for the first byte to learn the file size and whether ranges are supported.
+// The size is -1 when the server does not say.
+func probeHTTPSize(client *http.Client, fileURL string) (int64, bool, error) {
+	req, err := newHTTPRequest(fileURL, 0, 0)
+	if err != nil {
+		return 0, false, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// Content-Range: bytes 0-0/12345
+		if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+			if size, err := strconv.ParseInt(total, 10, 64); err == nil {
+				return size, true, nil
+			}
+		}
+		return -1, false, nil
+	case http.StatusOK:
+		return resp.ContentLength, false, nil
+	default:
+		return 0, false, fmt.Errorf("HTTP %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+}
+
+// DownloadHTTP saves fileURL to dest. When the server supports byte ranges the file is fetched
+// in up to connections parallel parts, and an interrupted download resumes from dest.part on
+// the next run. Without range support it falls back to a single stream.
+func DownloadHTTP(fileURL, dest string, connections int) error {
+	client := &http.Client{}
+	partFile := dest + ".part"
+	statePath := partFile + ".json"
+
+	size, ranged, err := probeHTTPSize(client, fileURL)
+	if err != nil {
+		return fmt.Errorf("failed to request %s: %v", fileURL, err)
+	}
+	if !ranged || size <= 0 {
+		CleanUpFiles(statePath)
+		if err := downloadHTTPStream(client, fileURL, partFile); err != nil {
+			return err
+		}
+		return os.Rename(partFile, dest)
+	}
+
+	state := loadHTTPDownloadState(statePath, partFile, fileURL, size)
+	if state == nil {
+		state = &httpDownloadState{URL: fileURL, Size: size, path: statePath}
+		if connections < 1 {
+			connections = 1
+		}
+		partSize := size / int64(connections)
+		if partSize < minPartSize {
+			partSize = minPartSize
+		}
+		for start := int64(0); start < size; start += partSize {
+			end := start + partSize - 1
+			if end >= size-1 || size-end-1 < partSize/2 {
+				end = size - 1 // fold a small tail into the last part
+			}
+			state.Parts = append(state.Parts, httpPart{Start: start, End: end})
+			if end == size-1 {
+				break
+			}
+		}
+	} else {
+		fmt.Printf("⏯️ Resuming download of %s\n", dest)
+	}
+
+	file, err := os.OpenFile(partFile, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", partFile, err)
+	}
+	defer file.Close()
+	if err := file.Truncate(size); err != nil {
+		return fmt.Errorf("failed to allocate %s: %v", partFile, err)
+	}
+
+	progress := newDownloadProgress(size, state)
+	defer progress.stop()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(state.Parts))
+	for i := range state.Parts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = downloadHTTPPart(client, file, state, i)
+		}(i)
+	}
+	wg.Wait()
+
+	state.mu.Lock()
+	saveErr := state.save()
+	state.mu.Unlock()
+	for _, err := range errs {
+		if err != nil {
+			if saveErr != nil {
+				return fmt.Errorf("%v (and could not save resume state: %v)", err, saveErr)
+			}
+			return err
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", partFile, err)
+	}
+	CleanUpFiles(statePath)
+	return os.Rename(partFile, dest)
+}
+
+// downloadHTTPPart fetches the unfinished remainder of one part, retrying dropped connections
+func downloadHTTPPart(client *http.Client, file *os.File, state *httpDownloadState, index int) error {
+	const attempts = 5
+	var lastErr error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		state.mu.Lock()
+		part := state.Parts[index]
+		state.mu.Unlock()
+
+		offset := part.Start + part.Written
+		if offset > part.End {
+			return nil
+		}
+
+		lastErr = func() error {
+			req, err := newHTTPRequest(state.URL, offset, part.End)
+			if err != nil {
+				return err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusPartialContent {
+				return fmt.Errorf("expected a partial response, got HTTP %d", resp.StatusCode)
+			}
+
+			buf := make([]byte, 256<<10)
+			for {
+				n, readErr := resp.Body.Read(buf)
+				if n > 0 {
+					if _, err := file.WriteAt(buf[:n], offset); err != nil {
+						return err
+					}
+					offset += int64(n)
+
+					state.mu.Lock()
+					state.Parts[index].Written = offset - part.Start
+					state.mu.Unlock()
+				}
+				if readErr == io.EOF {
+					if offset <= part.End {
+						return io.ErrUnexpectedEOF
+					}
+					return nil
+				}
+				if readErr != nil {
+					return readErr
+				}
+			}
+		}()
+		if lastErr == nil {
+			return nil
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+	return fmt.Errorf("failed to download bytes %d-%d of %s: %v", state.Parts[index].Start, state.Parts[index].End, state.URL, lastErr)
+}
+
+// downloadHTTPStream fetches the whole file over one connection
+func downloadHTTPStream(client *http.Client, fileURL, dest string) error {
+	req, err := newHTTPRequest(fileURL, 0, -1)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request %s: %v", fileURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: HTTP %d %s", fileURL, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	file, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", dest, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return fmt.Errorf("failed to download %s: %v", fileURL, err)
+	}
+	return file.Close()
+}
+
+// downloadProgress periodically prints progress and checkpoints the resume state
+type downloadProgress struct {
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newDownloadProgress(size int64, state *httpDownloadState) *downloadProgress {
+	p := &downloadProgress{done: make(chan struct{})}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				state.mu.Lock()
+				var written int64
+				for _, part := range state.Parts {
+					written += part.Written
+				}
+				state.save()
+				state.mu.Unlock()
+				fmt.Printf("⬇️ %.1f%% of %.1f MB\n", float64(written)*100/float64(size), float64(size)/(1<<20))
+			}
+		}
+	}()
+	return p
+}
+
+func (p *downloadProgress) stop() {
+	close(p.done)
+	p.wg.Wait()
+}
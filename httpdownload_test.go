@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadHTTPDownloadState(t *testing.T) {
+	const fileURL = "https://example.com/video.mp4"
+	dir := t.TempDir()
+	partFile := filepath.Join(dir, "video.mp4.part")
+	statePath := partFile + ".json"
+
+	saved := &httpDownloadState{URL: fileURL, Size: 100, Parts: []httpPart{{Start: 0, End: 99, Written: 40}}, path: statePath}
+	if err := saved.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if loadHTTPDownloadState(statePath, partFile, fileURL, 100) != nil {
+		t.Error("state loaded although the part file is missing")
+	}
+	if err := os.WriteFile(partFile, make([]byte, 40), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if loadHTTPDownloadState(statePath, partFile, fileURL, 100) != nil {
+		t.Error("state loaded although the part file is shorter than the download")
+	}
+	if err := os.Truncate(partFile, 100); err != nil {
+		t.Fatal(err)
+	}
+	state := loadHTTPDownloadState(statePath, partFile, fileURL, 100)
+	if state == nil || state.Parts[0].Written != 40 {
+		t.Fatalf("state = %+v, want the saved parts", state)
+	}
+	if loadHTTPDownloadState(statePath, partFile, fileURL, 200) != nil {
+		t.Error("state loaded although the size changed")
+	}
+	if loadHTTPDownloadState(statePath, partFile, "https://example.com/other.mp4", 100) != nil {
+		t.Error("state loaded for a different URL")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"regexp"
 	"syscall"
 
 	"time"
@@ -36,9 +37,124 @@ func main() {
 			return
 		}
 		DeleteAllBranches(os.Args[2:])
+	case "branches":
+		branchesCmd := flag.NewFlagSet("branches", flag.ExitOnError)
+		sortKey := branchesCmd.String("sort", "name", "Sort by name, date, ahead or behind")
+		pattern := branchesCmd.String("match", "", "Only show branches matching this glob")
+		merged := branchesCmd.Bool("merged", false, "Only show branches merged into the default branch")
+		unmerged := branchesCmd.Bool("unmerged", false, "Only show branches not merged into the default branch")
+		jsonOutput := branchesCmd.Bool("json", false, "Print branch status as JSON")
+
+		branchesCmd.Parse(os.Args[2:])
+
+		if *merged && *unmerged {
+			fmt.Println("Please specify only one of -merged and -unmerged.")
+			return
+		}
+
+		branches, defaultBranch, err := ListBranches(BranchFilter{Pattern: *pattern, Merged: *merged, Unmerged: *unmerged})
+		if err != nil {
+			log.Fatalf("Error listing branches: %v", err)
+		}
+		if err := SortBranches(branches, *sortKey); err != nil {
+			log.Fatalf("Error sorting branches: %v", err)
+		}
+		if err := PrintBranches(branches, defaultBranch, *jsonOutput); err != nil {
+			log.Fatalf("Error printing branches: %v", err)
+		}
+	case "commit-msg":
+		commitCmd := flag.NewFlagSet("commit-msg", flag.ExitOnError)
+		installHook := commitCmd.Bool("install-hook", false, "Install a prepare-commit-msg hook that pre-fills generated messages")
+		hookMode := commitCmd.Bool("hook", false, "Run as the prepare-commit-msg hook: <message-file> [source]")
+
+		commitCmd.Parse(os.Args[2:])
+
+		if *installHook {
+			if err := InstallCommitMsgHook(); err != nil {
+				log.Fatalf("Error installing hook: %v", err)
+			}
+			return
+		}
+
+		if *hookMode {
+			if commitCmd.NArg() < 1 {
+				log.Fatalf("Usage: commit-msg -hook <message-file> [source]")
+			}
+			if err := WriteCommitMessageForHook(commitCmd.Arg(0), commitCmd.Arg(1)); err != nil {
+				log.Fatalf("Error generating commit message: %v", err)
+			}
+			return
+		}
+
+		if err := CommitWithGeneratedMessage(); err != nil {
+			log.Fatalf("Error generating commit message: %v", err)
+		}
+	case "pr-description":
+		prCmd := flag.NewFlagSet("pr-description", flag.ExitOnError)
+		outputPath := prCmd.String("o", "", "Write the description to this file instead of stdout")
+		templatePath := prCmd.String("template", "", "Path to a custom prompt template (Go text/template)")
+
+		prCmd.Parse(os.Args[2:])
+
+		description, err := GeneratePRDescription(*templatePath)
+		if err != nil {
+			log.Fatalf("Error generating pull request description: %v", err)
+		}
+		if err := WritePRDescription(description, *outputPath); err != nil {
+			log.Fatalf("Error writing pull request description: %v", err)
+		}
+	case "changelog":
+		changelogCmd := flag.NewFlagSet("changelog", flag.ExitOnError)
+		from := changelogCmd.String("from", "", "Start ref (exclusive); defaults to the latest tag")
+		to := changelogCmd.String("to", "HEAD", "End ref (inclusive)")
+		version := changelogCmd.String("version", "Unreleased", "Heading for this changelog section")
+		polish := changelogCmd.Bool("polish", false, "Polish the wording with GPT")
+		prepend := changelogCmd.String("prepend", "", "Prepend the section to this file (e.g. CHANGELOG.md) instead of printing it")
+
+		changelogCmd.Parse(os.Args[2:])
+
+		entries, revRange, err := ChangelogCommits(*from, *to)
+		if err != nil {
+			log.Fatalf("Error reading commits: %v", err)
+		}
+		if len(entries) == 0 {
+			fmt.Printf("No commits found in %s.\n", revRange)
+			return
+		}
+
+		section := RenderChangelog(*version, entries)
+		if *polish {
+			section, err = PolishChangelog(section)
+			if err != nil {
+				log.Fatalf("Error polishing changelog: %v", err)
+			}
+		}
+
+		if *prepend != "" {
+			if err := PrependChangelog(*prepend, section); err != nil {
+				log.Fatalf("Error updating changelog: %v", err)
+			}
+			return
+		}
+		fmt.Print(section)
+	case "sync-branches":
+		syncCmd := flag.NewFlagSet("sync-branches", flag.ExitOnError)
+		strategy := syncCmd.String("strategy", "", "rebase or merge (default: SYNC_BRANCHES_STRATEGY or rebase)")
+		pattern := syncCmd.String("match", "", "Only sync branches matching this glob")
+		noFetch := syncCmd.Bool("no-fetch", false, "Skip git fetch before syncing")
+
+		syncCmd.Parse(os.Args[2:])
+
+		results, err := SyncBranches(SyncOptions{Strategy: *strategy, Pattern: *pattern, NoFetch: *noFetch})
+		if err != nil {
+			log.Fatalf("Error syncing branches: %v", err)
+		}
+		PrintSyncSummary(results)
 	case "transcribe":
 		cmd := flag.NewFlagSet("transcribe", flag.ExitOnError)
 		videoPath := cmd.String("video", "", "Path to the video file")
+		transcription := AddTranscriptionFlags(cmd)
+		from := cmd.String("from", "", "Import an existing SRT, WebVTT or JSON transcript instead of transcribing")
 		
 		cmd.Parse(os.Args[2:])
 
@@ -47,8 +163,17 @@ func main() {
 			return
 		}
 
+		if *from != "" {
+			if _, err := ImportTranscript(*from, *videoPath); err != nil {
+				log.Fatalf("Error importing transcript: %v", err)
+			}
+			return
+		}
+
 		// Step 2: Transcribe the audio to get the text
-		TranscribeAudio(*videoPath)
+		if _, err := TranscribeAudio(*videoPath, *transcription); err != nil {
+			log.Fatalf("Error transcribing video: %v", err)
+		}
 
 		// // Clean up temporary audio and video files
 		// if err := CleanUpFiles("./output/audio.wav", "./output/audio.m4a"); err != nil {
@@ -57,6 +182,7 @@ func main() {
 	case "script":
 		cmd := flag.NewFlagSet("transcribe", flag.ExitOnError)
 		videoPath := cmd.String("video", "", "Path to the video file")
+		transcription := AddTranscriptionFlags(cmd)
 		
 		cmd.Parse(os.Args[2:])
 
@@ -66,41 +192,279 @@ func main() {
 		}
 
 		// Step 2: Transcribe the audio to get the text
-		TranscribeAudio(*videoPath)
+		if _, err := TranscribeAudio(*videoPath, *transcription); err != nil {
+			log.Fatalf("Error transcribing video: %v", err)
+		}
+	case "edit":
+		editCmd := flag.NewFlagSet("edit", flag.ExitOnError)
+		videoPath := editCmd.String("video", "", "Path to the video file")
+		presetName := editCmd.String("preset", DefaultPresetName, "Encoding preset for the edited video")
+		exportOnly := editCmd.Bool("export", false, "Only write the editable transcript to output/edits")
+		noEditor := editCmd.Bool("no-editor", false, "Render the edits saved in output/edits without opening $EDITOR")
+		reset := editCmd.Bool("reset", false, "Discard earlier edits and start from the full transcript")
+		transcription := AddTranscriptionFlags(editCmd)
+
+		editCmd.Parse(os.Args[2:])
+
+		if *videoPath == "" {
+			fmt.Println("Please specify a video file.")
+			return
+		}
+		preset, err := GetPreset(*presetName)
+		if err != nil {
+			log.Fatalf("Error loading preset: %v", err)
+		}
+
+		opts := EditOptions{Transcription: *transcription, Preset: preset, ExportOnly: *exportOnly, NoEditor: *noEditor, Reset: *reset}
+		if _, err := EditVideo(*videoPath, opts); err != nil {
+			log.Fatalf("Error editing video: %v", err)
+		}
+	case "transcript-cache":
+		cacheCmd := flag.NewFlagSet("transcript-cache", flag.ExitOnError)
+		invalidate := cacheCmd.Bool("invalidate", false, "Remove the cached transcripts of the given files or keys so they are transcribed again")
+		all := cacheCmd.Bool("all", false, "With -invalidate, remove every cached transcript")
+
+		cacheCmd.Parse(os.Args[2:])
+
+		if *invalidate {
+			if cacheCmd.NArg() == 0 && !*all {
+				fmt.Println("Please specify files or cache keys to invalidate, or -all.")
+				return
+			}
+			if err := InvalidateTranscriptions(cacheCmd.Args(), *all); err != nil {
+				log.Fatalf("Error invalidating transcripts: %v", err)
+			}
+			return
+		}
+		if err := InspectTranscriptionCache(cacheCmd.Args()); err != nil {
+			log.Fatalf("Error reading transcription cache: %v", err)
+		}
+	case "record":
+		recordCmd := flag.NewFlagSet("record", flag.ExitOnError)
+		segmentLength := recordCmd.Duration("segment", 10*time.Minute, "Length of each rolling segment")
+		duration := recordCmd.Duration("duration", 0, "Stop after recording this long (default: until the stream ends or Ctrl+C)")
+		reconnects := recordCmd.Int("reconnects", 5, "Consecutive failed reconnects before giving up")
+		keepSegments := recordCmd.Bool("keep-segments", false, "Keep the segment files after stitching")
+		nameTemplate := recordCmd.String("o", "", "Output name template (default per provider)")
+
+		recordCmd.Parse(os.Args[2:])
+
+		if recordCmd.NArg() < 1 {
+			fmt.Println("Please provide a livestream URL.")
+			return
+		}
+
+		opts := RecordOptions{SegmentLength: *segmentLength, MaxDuration: *duration, MaxReconnects: *reconnects, KeepSegments: *keepSegments, NameTemplate: *nameTemplate}
+		if _, err := RecordStream(recordCmd.Arg(0), opts); err != nil {
+			log.Fatalf("Error recording stream: %v", err)
+		}
+	case "monitor":
+		monitorCmd := flag.NewFlagSet("monitor", flag.ExitOnError)
+		configPath := monitorCmd.String("config", "", "Monitor config file (default: MONITOR_CONFIG or ./input/monitor.json)")
+		once := monitorCmd.Bool("once", false, "Check the sources once and exit")
+		interval := monitorCmd.Duration("interval", 0, "Time between checks (default: the config interval or 15m)")
+		backfill := monitorCmd.Bool("backfill", false, "Download what new sources already list instead of only later posts")
+		workers := monitorCmd.Int("workers", 3, "Number of concurrent downloads")
+		archivePath := monitorCmd.String("archive", "./output/download_archive.txt", "Archive of downloaded URLs to skip (empty to disable)")
+
+		monitorCmd.Parse(os.Args[2:])
+
+		config, err := LoadMonitorConfig(*configPath)
+		if err != nil {
+			log.Fatalf("Error loading monitor config: %v", err)
+		}
+
+		queueOpts := DownloadQueueOptions{DownloadOptions: DownloadOptions{NameTemplate: config.NameTemplate}, Workers: *workers, Retries: 1, ArchivePath: *archivePath}
+		if config.Preset != "" {
+			if queueOpts.Preset, err = GetPreset(config.Preset); err != nil {
+				log.Fatalf("Error loading preset: %v", err)
+			}
+		}
+
+		if err := RunMonitor(config, MonitorOptions{Queue: queueOpts, Once: *once, Interval: *interval, Backfill: *backfill}); err != nil {
+			log.Fatalf("Error monitoring sources: %v", err)
+		}
+	case "dedupe":
+		dedupeCmd := flag.NewFlagSet("dedupe", flag.ExitOnError)
+		dir := dedupeCmd.String("dir", "./output", "Directory to scan for duplicate media")
+		remove := dedupeCmd.Bool("remove", false, "Delete every duplicate except the oldest copy")
+
+		dedupeCmd.Parse(os.Args[2:])
+
+		if err := Dedupe(*dir, *remove); err != nil {
+			log.Fatalf("Error finding duplicates: %v", err)
+		}
+	case "cookies":
+		if err := CheckCookies(); err != nil {
+			log.Fatalf("Error checking cookies: %v", err)
+		}
 	case "download":
 		downloadCommand := flag.NewFlagSet("download", flag.ExitOnError)
 		xFlag := downloadCommand.String("x", "", "Download video from X.com (Twitter) post link")
+		providerName := downloadCommand.String("provider", "", "Force a provider (youtube, x, bluesky, reddit, generic) instead of detecting it from the URL")
+		queue := downloadCommand.Bool("queue", false, "Download every URL from the arguments, -file or stdin through a worker pool")
+		listFile := downloadCommand.String("file", "", "File with one URL per line to queue (- for stdin)")
+		workers := downloadCommand.Int("workers", 3, "Number of concurrent queued downloads")
+		retries := downloadCommand.Int("retries", 3, "Retries per failed queued download")
+		archivePath := downloadCommand.String("archive", "./output/download_archive.txt", "Archive of downloaded URLs to skip (empty to disable)")
+		nameTemplate := downloadCommand.String("o", "", "Output name template, e.g. {uploader}/{date}-{title}.{ext} (default per provider)")
+		presetName := downloadCommand.String("preset", "", "Encoding preset: premiere, web, archive, shorts-vertical, audio-only or a custom one")
+		start := downloadCommand.String("start", "", "Only download from this time (seconds, MM:SS or HH:MM:SS)")
+		end := downloadCommand.String("end", "", "Only download up to this time (seconds, MM:SS or HH:MM:SS)")
+		sections := downloadCommand.String("sections", "", "Only download this section, e.g. 10:00-15:00")
+		captions := downloadCommand.Bool("captions", false, "Also save captions to output/transcriptions for use instead of Whisper")
+		captionLang := downloadCommand.String("caption-lang", "en", "Caption language for -captions")
+		captionsOnly := downloadCommand.Bool("captions-only", false, "Only save captions, skip the video")
+		playlistURL := downloadCommand.String("playlist", "", "Download the videos of this playlist")
+		channel := downloadCommand.String("channel", "", "Download the uploads of this channel URL or @handle")
+		after := downloadCommand.String("after", "", "With -playlist/-channel: only videos published on or after this date (YYYY-MM-DD)")
+		before := downloadCommand.String("before", "", "With -playlist/-channel: only videos published before this date (YYYY-MM-DD)")
+		titleMatch := downloadCommand.String("match-title", "", "With -playlist/-channel: only videos whose title matches this regular expression")
+		minDuration := downloadCommand.String("min-duration", "", "With -playlist/-channel: skip videos shorter than this (seconds, MM:SS or HH:MM:SS)")
+		maxDuration := downloadCommand.String("max-duration", "", "With -playlist/-channel: skip videos longer than this (seconds, MM:SS or HH:MM:SS)")
+		maxCount := downloadCommand.Int("max-count", 0, "With -playlist/-channel: download at most this many new videos")
+		duplicates := downloadCommand.String("duplicates", DuplicatesWarn, "When a download matches a file already in the library: warn, skip (delete the new copy) or off")
+		audioOnly := downloadCommand.Bool("audio", false, "Download only the audio")
+		audioFormat := downloadCommand.String("audio-format", defaultAudioFormat, "Audio format for -audio: wav, mp3, m4a or flac")
+		sampleRate := downloadCommand.Int("sample-rate", 0, "Audio sample rate in Hz for -audio (default: keep the source rate)")
+		channels := downloadCommand.Int("channels", 0, "Audio channels for -audio, e.g. 1 for mono (default: keep the source layout)")
+		normalize := downloadCommand.Bool("normalize", false, "Normalize -audio loudness to -16 LUFS (EBU R128)")
+		connections := downloadCommand.Int("connections", defaultConnections, "Parallel connections for direct file and HLS (.m3u8) downloads")
 
 		if err := downloadCommand.Parse(os.Args[2:]); err != nil {
 			log.Fatalf("Error parsing download command: %v", err)
 		}
 
-		if *xFlag != "" {
-			// Download video from X.com post
-			if err := DownloadFromX(*xFlag); err != nil {
-				log.Fatalf("Error downloading from X.com: %v", err)
+		timeRange, err := ParseTimeRange(*start, *end, *sections)
+		if err != nil {
+			log.Fatalf("Error parsing time range: %v", err)
+		}
+
+		downloadOpts := DownloadOptions{NameTemplate: *nameTemplate, TimeRange: timeRange, Connections: *connections}
+		if *captions || *captionsOnly {
+			downloadOpts.CaptionLang = *captionLang
+		}
+		switch *duplicates {
+		case DuplicatesWarn, DuplicatesSkip, DuplicatesOff:
+			downloadOpts.Duplicates = *duplicates
+		default:
+			log.Fatalf("Invalid -duplicates %q, expected warn, skip or off", *duplicates)
+		}
+		if *audioOnly {
+			if *presetName != "" {
+				log.Fatalf("Use either -audio or -preset, not both")
+			}
+			downloadOpts.Audio = &AudioOptions{Format: *audioFormat, SampleRate: *sampleRate, Channels: *channels, Normalize: *normalize}
+			if err := downloadOpts.Audio.Validate(); err != nil {
+				log.Fatalf("Error in audio options: %v", err)
+			}
+		}
+		var provider Provider
+		if *providerName != "" {
+			if provider, err = GetProvider(*providerName); err != nil {
+				log.Fatalf("Error selecting provider: %v", err)
+			}
+		} else if *xFlag != "" {
+			provider, _ = GetProvider("x")
+		}
+		if *presetName != "" {
+			preset, err := GetPreset(*presetName)
+			if err != nil {
+				log.Fatalf("Error loading preset: %v", err)
+			}
+			downloadOpts.Preset = preset
+		}
+
+		queueOpts := DownloadQueueOptions{DownloadOptions: downloadOpts, Provider: provider, Workers: *workers, Retries: *retries, ArchivePath: *archivePath}
+
+		if *playlistURL != "" || *channel != "" {
+			if *playlistURL != "" && *channel != "" {
+				log.Fatalf("Use either -playlist or -channel, not both")
+			}
+			listURL := *playlistURL
+			if *channel != "" {
+				listURL = ChannelURL(*channel)
+			}
+
+			filter := PlaylistFilter{MaxCount: *maxCount, NewestFirst: *channel != ""}
+			if filter.After, err = ParseDay(*after); err != nil {
+				log.Fatalf("Error parsing -after: %v", err)
+			}
+			if filter.Before, err = ParseDay(*before); err != nil {
+				log.Fatalf("Error parsing -before: %v", err)
+			}
+			if *titleMatch != "" {
+				if filter.Title, err = regexp.Compile(*titleMatch); err != nil {
+					log.Fatalf("Error parsing -match-title: %v", err)
+				}
+			}
+			if *minDuration != "" {
+				if filter.MinDuration, err = ParseTimestamp(*minDuration); err != nil {
+					log.Fatalf("Error parsing -min-duration: %v", err)
+				}
+			}
+			if *maxDuration != "" {
+				if filter.MaxDuration, err = ParseTimestamp(*maxDuration); err != nil {
+					log.Fatalf("Error parsing -max-duration: %v", err)
+				}
+			}
+
+			_, reports, err := DownloadPlaylist(listURL, filter, queueOpts)
+			if err != nil {
+				log.Fatalf("Error downloading playlist: %v", err)
+			}
+			if failed := PrintDownloadReport(reports); failed > 0 {
+				os.Exit(1)
 			}
 			return
 		}
 
-		// Default behavior: Expect a generic video URL
-		if downloadCommand.NArg() < 1 {
+		if *queue || *listFile != "" {
+			urls, err := ReadURLList(downloadCommand.Args(), *listFile)
+			if err != nil {
+				log.Fatalf("Error reading URLs: %v", err)
+			}
+			if len(urls) == 0 {
+				fmt.Println("Please provide at least one video URL.")
+				return
+			}
+
+			reports, err := RunDownloadQueue(urls, queueOpts)
+			if err != nil {
+				log.Fatalf("Error running download queue: %v", err)
+			}
+			if failed := PrintDownloadReport(reports); failed > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+
+		// -x predates provider detection and is kept as a shorthand for -provider x
+		videoURL := *xFlag
+		if videoURL == "" {
+			videoURL = downloadCommand.Arg(0)
+		}
+		if videoURL == "" {
 			fmt.Println("Please provide a video URL.")
 			return
 		}
-		videoURL := downloadCommand.Arg(0)
-		_, err := url.Parse(videoURL)
+		_, err = url.Parse(videoURL)
 		if err != nil {
 			log.Fatalf("Error parsing video URL: %v", err)
 		}
 
-		if err := DownloadVideoAsMP4(videoURL); err != nil {
-			log.Fatalf("Error downloading video: %v", err)
+		if *captionsOnly {
+			if _, err := DownloadCaptionsOnly(videoURL, downloadOpts); err != nil {
+				log.Fatalf("Error downloading captions: %v", err)
+			}
+			return
 		}
 
-		// Clean up temporary files
-		if err := CleanUpFiles("./output/audio.wav", "./output/audio.m4a"); err != nil {
-			log.Printf("Error cleaning up files: %v", err)
+		if provider == nil {
+			provider = ProviderFor(videoURL)
+		}
+		if _, err := downloadWith(provider, videoURL, downloadOpts); err != nil {
+			log.Fatalf("Error downloading: %v", err)
 		}
 
 	case "convert-to-speech":
@@ -122,10 +486,11 @@ func main() {
 		splitCmd := flag.NewFlagSet("split-video", flag.ExitOnError)
 		thresholdFlag := splitCmd.Float64("threshold", -40, "Silence detection threshold in dB (e.g., -40)")
 		durationFlag := splitCmd.Float64("duration", 2.0, "Minimum silence duration in seconds")
+		presetName := splitCmd.String("preset", "", "Encoding preset for the clips (default: copy streams without re-encoding)")
 
 		// Check if there are enough arguments before parsing
 		if len(os.Args) < 3 {
-			log.Fatalf("Usage: split-video [-threshold=<value>] [-duration=<value>] <video-file>")
+			log.Fatalf("Usage: split-video [-threshold=<value>] [-duration=<value>] [-preset=<name>] <video-file>")
 		}
 
 		// Parse the flags for this command
@@ -135,14 +500,22 @@ func main() {
 
 		// Validate arguments
 		if splitCmd.NArg() < 1 {
-			log.Fatalf("Usage: split-video [-threshold=<value>] [-duration=<value>] <video-file>")
+			log.Fatalf("Usage: split-video [-threshold=<value>] [-duration=<value>] [-preset=<name>] <video-file>")
 		}
 
 		videoFile := splitCmd.Arg(0)
 		log.Printf("Splitting video: %s with threshold=%f dB and duration=%f seconds", videoFile, *thresholdFlag, *durationFlag)
 
+		var preset *EncodingPreset
+		if *presetName != "" {
+			var err error
+			if preset, err = GetPreset(*presetName); err != nil {
+				log.Fatalf("Error loading preset: %v", err)
+			}
+		}
+
 		// Call the SplitVideo function
-		err := SplitVideo(videoFile, *thresholdFlag, *durationFlag)
+		err := SplitVideo(videoFile, *thresholdFlag, *durationFlag, preset)
 		if err != nil {
 			log.Fatalf("Error splitting video: %v", err)
 		}
@@ -152,6 +525,7 @@ func main() {
 		platforms := publishCmd.String("platforms", "youtube", "Platforms to publish to (comma-separated)")
 		thumbnailPath := publishCmd.String("thumbnail", "", "Path to the custom thumbnail image")
 		videoPath := publishCmd.String("video", "", "Path to the video file")
+		transcription := AddTranscriptionFlags(publishCmd)
 
 		publishCmd.Parse(os.Args[2:])
 
@@ -160,7 +534,7 @@ func main() {
 			return
 		}
 
-		err := PublishWithAutoGeneratedMetadata(*videoPath, *hashtags, *platforms, *thumbnailPath)
+		err := PublishWithAutoGeneratedMetadata(*videoPath, *hashtags, *platforms, *thumbnailPath, *transcription)
 		if err != nil {
 			log.Fatalf("Error publishing video: %v", err)
 		}
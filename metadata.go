@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// VideoMetadata is the subset of yt-dlp's --dump-json output the tools care about
+type VideoMetadata struct {
+	ID           string  `json:"id"`
+	Title        string  `json:"title"`
+	Uploader     string  `json:"uploader"`
+	UploaderID   string  `json:"uploader_id"`
+	UploadDate   string  `json:"upload_date"` // YYYYMMDD
+	Duration     float64 `json:"duration"`
+	Extractor    string  `json:"extractor_key"`
+	Ext          string  `json:"ext"`
+	WebpageURL   string  `json:"webpage_url"`
+	VideoCodec   string  `json:"vcodec"`
+	AudioCodec   string  `json:"acodec"`
+	Description  string  `json:"description"`
+	ThumbnailURL string  `json:"thumbnail"`
+}
+
+// FetchVideoMetadata asks yt-dlp for a video's metadata without downloading it
+func FetchVideoMetadata(videoURL string) (*VideoMetadata, error) {
+	args := append([]string{"--dump-json", "--no-playlist", "--skip-download"}, ytDLPAuthArgs(videoURL)...)
+	cmd := exec.Command("yt-dlp", append(args, videoURL)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching video metadata: %v", err)
+	}
+
+	var metadata VideoMetadata
+	if err := json.Unmarshal(output, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse video metadata: %v", err)
+	}
+	return &metadata, nil
+}
+
+// UploadTime parses the YYYYMMDD upload date, returning the zero time if it is missing
+func (m *VideoMetadata) UploadTime() time.Time {
+	t, err := time.Parse("20060102", strings.TrimSpace(m.UploadDate))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MonitorSource is one account or channel to watch
+type MonitorSource struct {
+	Type string `json:"type"` // youtube, x or bluesky
+	ID   string `json:"id"`   // YouTube channel ID or @handle, X account, BlueSky handle or DID
+	Name string `json:"name"` // label for logs and notifications; defaults to ID
+}
+
+// Key identifies the source in the state file
+func (s MonitorSource) Key() string {
+	return s.Type + ":" + s.ID
+}
+
+// Label is the name shown in logs and notifications
+func (s MonitorSource) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.ID
+}
+
+// MonitorConfig is read from MONITOR_CONFIG (default ./input/monitor.json)
+type MonitorConfig struct {
+	Interval     string          `json:"interval"` // Go duration between polls, e.g. 15m
+	Webhook      string          `json:"webhook"`  // optional URL notified of new downloads
+	Preset       string          `json:"preset"`   // encoding preset for downloads
+	NameTemplate string          `json:"nameTemplate"`
+	Sources      []MonitorSource `json:"sources"`
+}
+
+// MonitorItem is a post or video found while polling a source
+type MonitorItem struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	Published time.Time `json:"published,omitempty"`
+}
+
+// monitorSourceState is what the monitor remembers per source between polls
+type monitorSourceState struct {
+	LastChecked time.Time      `json:"lastChecked"`
+	ChannelID   string         `json:"channelId,omitempty"` // resolved YouTube @handle
+	Seen        []string       `json:"seen"`                // item IDs, newest last
+	Failures    map[string]int `json:"failures,omitempty"`  // failed download attempts of unseen items
+}
+
+// maxSeenItems bounds the remembered IDs per source; feeds only show recent posts anyway
+const maxSeenItems = 500
+
+// maxItemFailures is how many polls may fail to download an item before it is given up on
+const maxItemFailures = 3
+
+// MonitorState is saved to ./output/monitor_state.json after every poll
+type MonitorState struct {
+	path    string
+	Sources map[string]*monitorSourceState `json:"sources"`
+}
+
+// LoadMonitorConfig reads the monitor configuration
+func LoadMonitorConfig(path string) (*MonitorConfig, error) {
+	if path == "" {
+		path = os.Getenv("MONITOR_CONFIG")
+	}
+	if path == "" {
+		path = "./input/monitor.json"
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read monitor config: %v", err)
+	}
+
+	var config MonitorConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse monitor config %s: %v", path, err)
+	}
+	if len(config.Sources) == 0 {
+		return nil, fmt.Errorf("monitor config %s has no sources", path)
+	}
+	for _, source := range config.Sources {
+		switch source.Type {
+		case "youtube", "x", "bluesky":
+		default:
+			return nil, fmt.Errorf("unknown source type %q in %s (expected youtube, x or bluesky)", source.Type, path)
+		}
+		if source.ID == "" {
+			return nil, fmt.Errorf("a %s source in %s has no id", source.Type, path)
+		}
+	}
+	return &config, nil
+}
+
+// LoadMonitorState reads the state file, treating a missing file as empty
+func LoadMonitorState(path string) (*MonitorState, error) {
+	state := &MonitorState{path: path, Sources: map[string]*monitorSourceState{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read monitor state: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse monitor state %s: %v", path, err)
+	}
+	if state.Sources == nil {
+		state.Sources = map[string]*monitorSourceState{}
+	}
+	return state, nil
+}
+
+// Save writes the state file
+func (s *MonitorState) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write monitor state: %v", err)
+	}
+	return nil
+}
+
+// source returns the state of a source, creating it on first use
+func (s *MonitorState) source(key string) (*monitorSourceState, bool) {
+	state, ok := s.Sources[key]
+	if !ok {
+		state = &monitorSourceState{}
+		s.Sources[key] = state
+	}
+	return state, ok
+}
+
+// markSeen remembers item IDs, dropping the oldest past maxSeenItems
+func (s *monitorSourceState) markSeen(ids ...string) {
+	for _, id := range ids {
+		delete(s.Failures, id)
+	}
+	s.Seen = append(s.Seen, ids...)
+	if len(s.Seen) > maxSeenItems {
+		s.Seen = s.Seen[len(s.Seen)-maxSeenItems:]
+	}
+}
+
+// recordFailure counts a failed download of an item and returns the failures so far.
+// After maxItemFailures the item is marked seen so later polls stop retrying it.
+func (s *monitorSourceState) recordFailure(id string) int {
+	if s.Failures == nil {
+		s.Failures = map[string]int{}
+	}
+	s.Failures[id]++
+	failures := s.Failures[id]
+	if failures >= maxItemFailures {
+		s.markSeen(id)
+	}
+	return failures
+}
+
+// hasSeen reports whether an item was handled before
+func (s *monitorSourceState) hasSeen(id string) bool {
+	for _, seen := range s.Seen {
+		if seen == id {
+			return true
+		}
+	}
+	return false
+}
+
+// getJSON fetches a URL and decodes its JSON body
+func getJSON(requestURL string, target interface{}) error {
+	resp, err := http.Get(requestURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d %s: %s", resp.StatusCode, http.StatusText(resp.StatusCode), body)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// resolveYouTubeChannelID turns a @handle into the channel ID the RSS feed needs
+func resolveYouTubeChannelID(handle string) (string, error) {
+	channelURL := ChannelURL(handle)
+	args := append([]string{"--print", "channel_id", "--playlist-items", "1"}, ytDLPAuthArgs(channelURL)...)
+	output, err := exec.Command("yt-dlp", append(args, channelURL)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve channel %s: %v", handle, err)
+	}
+	channelID := strings.TrimSpace(string(output))
+	if channelID == "" || channelID == "NA" {
+		return "", fmt.Errorf("failed to resolve channel %s", handle)
+	}
+	return channelID, nil
+}
+
+// youtubeFeed is the part of a channel's Atom feed the monitor reads
+type youtubeFeed struct {
+	Entries []struct {
+		VideoID   string    `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+		Title     string    `xml:"title"`
+		Published time.Time `xml:"published"`
+	} `xml:"entry"`
+}
+
+// pollYouTube reads the channel's RSS feed, which lists its 15 newest uploads
+func pollYouTube(channelID string) ([]MonitorItem, error) {
+	resp, err := http.Get("https://www.youtube.com/feeds/videos.xml?channel_id=" + url.QueryEscape(channelID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	var feed youtubeFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to parse channel feed: %v", err)
+	}
+	var items []MonitorItem
+	for _, entry := range feed.Entries {
+		items = append(items, MonitorItem{
+			ID:        entry.VideoID,
+			Title:     entry.Title,
+			URL:       "https://www.youtube.com/watch?v=" + entry.VideoID,
+			Published: entry.Published,
+		})
+	}
+	return items, nil
+}
+
+// pollX lists the newest media posts of an account with yt-dlp
+func pollX(account string) ([]MonitorItem, error) {
+	entries, err := ListPlaylist("https://x.com/"+strings.TrimPrefix(account, "@")+"/media", "--playlist-items", "1-20")
+	if err != nil {
+		return nil, err
+	}
+	var items []MonitorItem
+	for _, entry := range entries {
+		items = append(items, MonitorItem{ID: entry.ID, Title: entry.Title, URL: entry.VideoURL(), Published: entry.UploadTime()})
+	}
+	return items, nil
+}
+
+// blueskyAuthorFeed is the part of app.bsky.feed.getAuthorFeed the monitor reads
+type blueskyAuthorFeed struct {
+	Feed []struct {
+		Post struct {
+			URI    string `json:"uri"` // at://<did>/app.bsky.feed.post/<rkey>
+			Author struct {
+				Handle string `json:"handle"`
+			} `json:"author"`
+			Record struct {
+				Text      string    `json:"text"`
+				CreatedAt time.Time `json:"createdAt"`
+			} `json:"record"`
+		} `json:"post"`
+	} `json:"feed"`
+}
+
+// pollBluesky reads an author's recent video posts from the public AppView API
+func pollBluesky(actor string) ([]MonitorItem, error) {
+	query := url.Values{"actor": {actor}, "filter": {"posts_with_video"}, "limit": {"30"}}
+	var feed blueskyAuthorFeed
+	if err := getJSON("https://public.api.bsky.app/xrpc/app.bsky.feed.getAuthorFeed?"+query.Encode(), &feed); err != nil {
+		return nil, fmt.Errorf("failed to fetch BlueSky feed: %v", err)
+	}
+
+	var items []MonitorItem
+	for _, entry := range feed.Feed {
+		post := entry.Post
+		rkey := post.URI[strings.LastIndex(post.URI, "/")+1:]
+		items = append(items, MonitorItem{
+			ID:        post.URI,
+			Title:     post.Record.Text,
+			URL:       fmt.Sprintf("https://bsky.app/profile/%s/post/%s", post.Author.Handle, rkey),
+			Published: post.Record.CreatedAt,
+		})
+	}
+	return items, nil
+}
+
+// pollSource fetches the recent items of a source
+func pollSource(source MonitorSource, state *monitorSourceState) ([]MonitorItem, error) {
+	switch source.Type {
+	case "youtube":
+		channelID := source.ID
+		if strings.HasPrefix(channelID, "@") {
+			if state.ChannelID == "" {
+				resolved, err := resolveYouTubeChannelID(channelID)
+				if err != nil {
+					return nil, err
+				}
+				state.ChannelID = resolved
+			}
+			channelID = state.ChannelID
+		}
+		return pollYouTube(channelID)
+	case "x":
+		return pollX(source.ID)
+	case "bluesky":
+		return pollBluesky(source.ID)
+	}
+	return nil, fmt.Errorf("unknown source type %q", source.Type)
+}
+
+// MonitorNotification is posted to the webhook after a poll that downloaded something.
+// Text makes it readable by Slack-style incoming webhooks as is.
+type MonitorNotification struct {
+	Text  string                    `json:"text"`
+	Items []MonitorNotificationItem `json:"items"`
+}
+
+// MonitorNotificationItem is one new post in a notification
+type MonitorNotificationItem struct {
+	Source string `json:"source"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	File   string `json:"file,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// notifyWebhook posts a notification, reporting failures without stopping the monitor
+func notifyWebhook(webhook string, notification MonitorNotification) {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		fmt.Printf("⚠️ Could not encode webhook notification: %v\n", err)
+		return
+	}
+	resp, err := http.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("⚠️ Webhook failed: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		fmt.Printf("⚠️ Webhook returned HTTP %d\n", resp.StatusCode)
+	}
+}
+
+// MonitorOptions configures RunMonitor
+type MonitorOptions struct {
+	Queue    DownloadQueueOptions
+	Once     bool          // poll once and return, e.g. from cron
+	Interval time.Duration // overrides the config interval when set
+	Backfill bool          // download what a source already lists the first time it is polled
+}
+
+// PollOnce checks every source, downloads new items through the download queue, records
+// them in the state and notifies the webhook. The first poll of a source only records what
+// it lists unless backfill is set, so adding a source doesn't download its whole history.
+func PollOnce(config *MonitorConfig, state *MonitorState, opts MonitorOptions) error {
+	type pending struct {
+		source MonitorSource
+		item   MonitorItem
+	}
+	var queue []pending
+
+	for _, source := range config.Sources {
+		sourceState, known := state.source(source.Key())
+		items, err := pollSource(source, sourceState)
+		if err != nil {
+			fmt.Printf("⚠️ Could not check %s %s: %v\n", source.Type, source.Label(), err)
+			if !known {
+				delete(state.Sources, source.Key()) // still needs its first, baseline poll
+			}
+			continue
+		}
+		sourceState.LastChecked = time.Now()
+
+		var fresh []MonitorItem
+		for _, item := range items {
+			if item.ID != "" && !sourceState.hasSeen(item.ID) {
+				fresh = append(fresh, item)
+			}
+		}
+
+		if !known && !opts.Backfill {
+			fmt.Printf("👀 Now watching %s %s (%d existing items skipped)\n", source.Type, source.Label(), len(fresh))
+			for _, item := range fresh {
+				sourceState.markSeen(item.ID)
+			}
+			continue
+		}
+		fmt.Printf("🔎 %s %s: %d new\n", source.Type, source.Label(), len(fresh))
+		for _, item := range fresh {
+			queue = append(queue, pending{source, item})
+		}
+	}
+
+	if len(queue) > 0 {
+		urls := make([]string, len(queue))
+		for i, p := range queue {
+			urls[i] = p.item.URL
+		}
+		reports, err := RunDownloadQueue(urls, opts.Queue)
+		if err != nil {
+			return err
+		}
+		PrintDownloadReport(reports)
+
+		notification := MonitorNotification{}
+		downloaded, failed := 0, 0
+		for i, report := range reports {
+			p := queue[i]
+			sourceState := state.Sources[p.source.Key()]
+			notified := MonitorNotificationItem{Source: p.source.Type + " " + p.source.Label(), Title: p.item.Title, URL: p.item.URL, File: report.OutputFile}
+			if report.Err != nil {
+				// Leave failed items unseen so the next poll tries again, but only
+				// notify about the first failure and give up after maxItemFailures
+				failures := sourceState.recordFailure(p.item.ID)
+				if failures >= maxItemFailures {
+					fmt.Printf("⚠️ Giving up on %s after %d failed polls\n", p.item.URL, failures)
+				}
+				if failures > 1 {
+					continue
+				}
+				notified.Error = report.Err.Error()
+				failed++
+			} else {
+				sourceState.markSeen(p.item.ID)
+				downloaded++
+			}
+			notification.Items = append(notification.Items, notified)
+		}
+		if config.Webhook != "" && len(notification.Items) > 0 {
+			notification.Text = fmt.Sprintf("Downloaded %d new item(s), %d failed", downloaded, failed)
+			notifyWebhook(config.Webhook, notification)
+		}
+	}
+
+	return state.Save()
+}
+
+// RunMonitor polls the configured sources until interrupted, or once with opts.Once
+func RunMonitor(config *MonitorConfig, opts MonitorOptions) error {
+	interval := opts.Interval
+	if interval == 0 {
+		interval = 15 * time.Minute
+		if config.Interval != "" {
+			parsed, err := time.ParseDuration(config.Interval)
+			if err != nil {
+				return fmt.Errorf("invalid monitor interval %q: %v", config.Interval, err)
+			}
+			interval = parsed
+		}
+	}
+
+	state, err := LoadMonitorState("./output/monitor_state.json")
+	if err != nil {
+		return err
+	}
+
+	for {
+		fmt.Printf("⏱️ Checking %d source(s) at %s\n", len(config.Sources), time.Now().Format("2006-01-02 15:04:05"))
+		if err := PollOnce(config, state, opts); err != nil {
+			if opts.Once {
+				return err
+			}
+			fmt.Printf("⚠️ %v\n", err)
+		}
+		if opts.Once {
+			return nil
+		}
+		fmt.Printf("💤 Next check in %s\n", interval)
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestRecordFailure(t *testing.T) {
+	var s monitorSourceState
+	for want := 1; want < maxItemFailures; want++ {
+		if got := s.recordFailure("a"); got != want {
+			t.Fatalf("recordFailure = %d, want %d", got, want)
+		}
+		if s.hasSeen("a") {
+			t.Fatalf("item seen after %d failure(s), want it retried", want)
+		}
+	}
+	if got := s.recordFailure("a"); got != maxItemFailures || !s.hasSeen("a") {
+		t.Errorf("after %d failures: count %d, seen %v; want the item given up on", maxItemFailures, got, s.hasSeen("a"))
+	}
+	if _, ok := s.Failures["a"]; ok {
+		t.Error("failure count kept after the item was marked seen")
+	}
+
+	s.recordFailure("b")
+	s.markSeen("b")
+	if len(s.Failures) != 0 {
+		t.Errorf("Failures = %v after a successful retry, want empty", s.Failures)
+	}
+}
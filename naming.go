@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Default output name templates, relative to ./output. Override them per provider with
+// DOWNLOAD_TEMPLATE_<PROVIDER> (e.g. DOWNLOAD_TEMPLATE_YOUTUBE) or for everything with DOWNLOAD_TEMPLATE.
+var defaultNameTemplates = map[string]string{
+	"youtube": "{uploader}/{date}-{title}.{ext}",
+	"x":       "x/{uploader}/{date}-{id}.{ext}", // post text makes poor file names
+	"bluesky": "bluesky/{uploader}/{date}-{id}.{ext}",
+	"reddit":  "reddit/{date}-{title}.{ext}",
+	"":        "{date}-{title}.{ext}",
+}
+
+// maxNameFieldRunes keeps long titles from producing file names the filesystem rejects
+const maxNameFieldRunes = 100
+
+var templateField = regexp.MustCompile(`\{(\w+)\}`)
+
+// reservedOutputPaths holds names handed out but not yet written, so concurrent
+// downloads of videos with the same title do not pick the same file
+var (
+	reservedOutputPaths   = map[string]bool{}
+	reservedOutputPathsMu sync.Mutex
+)
+
+// NameTemplateFor returns the configured template for a provider
+func NameTemplateFor(provider string) string {
+	provider = strings.ToLower(provider)
+	if t := os.Getenv("DOWNLOAD_TEMPLATE_" + strings.ToUpper(provider)); provider != "" && t != "" {
+		return t
+	}
+	if t := os.Getenv("DOWNLOAD_TEMPLATE"); t != "" {
+		return t
+	}
+	if t, ok := defaultNameTemplates[provider]; ok {
+		return t
+	}
+	return defaultNameTemplates[""]
+}
+
+// nameFieldValue sanitizes a metadata value for use inside a path component
+func nameFieldValue(value string) string {
+	value = strings.TrimSpace(sanitizeFileName(value))
+	value = strings.Trim(value, ".") // avoid hidden files and "." / ".." components
+	if runes := []rune(value); len(runes) > maxNameFieldRunes {
+		value = strings.TrimSpace(string(runes[:maxNameFieldRunes]))
+	}
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+// RenderNameTemplate fills a template such as "{uploader}/{date}-{title}.{ext}" from metadata.
+// Supported fields: title, uploader, date, id, provider, ext and uuid. Every value is passed
+// through sanitizeFileName, so directories only come from the template itself.
+func RenderNameTemplate(template string, metadata *VideoMetadata, ext string) (string, error) {
+	if metadata == nil {
+		metadata = &VideoMetadata{}
+	}
+
+	date := metadata.UploadTime()
+	if date.IsZero() {
+		date = time.Now()
+	}
+	uploader := metadata.Uploader
+	if uploader == "" {
+		uploader = metadata.UploaderID
+	}
+
+	values := map[string]string{
+		"title":    metadata.Title,
+		"uploader": uploader,
+		"date":     date.Format("2006-01-02"),
+		"id":       metadata.ID,
+		"provider": strings.ToLower(metadata.Extractor),
+		"ext":      strings.TrimPrefix(ext, "."),
+		"uuid":     uuid.New().String(),
+	}
+
+	var unknown []string
+	name := templateField.ReplaceAllStringFunc(template, func(field string) string {
+		key := strings.ToLower(field[1 : len(field)-1])
+		value, ok := values[key]
+		if !ok {
+			unknown = append(unknown, field)
+			return field
+		}
+		return nameFieldValue(value)
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown template fields %s", strings.Join(unknown, ", "))
+	}
+
+	name = filepath.Clean(name)
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "..") {
+		return "", fmt.Errorf("template %q must produce a path inside the output directory", template)
+	}
+	return name, nil
+}
+
+// ReserveOutputPath turns a rendered name into a free path under outputDir, appending
+// " (2)", " (3)"... on collisions, and creates its parent directory.
+// Call ReleaseOutputPath once the file is written (or the download failed).
+func ReserveOutputPath(outputDir, name string) (string, error) {
+	path := filepath.Join(outputDir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	reservedOutputPathsMu.Lock()
+	defer reservedOutputPathsMu.Unlock()
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	candidate := path
+	for n := 2; ; n++ {
+		if _, err := os.Stat(candidate); os.IsNotExist(err) && !reservedOutputPaths[candidate] {
+			break
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+
+	reservedOutputPaths[candidate] = true
+	return candidate, nil
+}
+
+// ReleaseOutputPath forgets a reservation made by ReserveOutputPath
+func ReleaseOutputPath(path string) {
+	reservedOutputPathsMu.Lock()
+	defer reservedOutputPathsMu.Unlock()
+	delete(reservedOutputPaths, path)
+}
+
+// ResolveOutputPath fetches metadata for the URL from its provider and reserves an output path for it.
+// An empty template selects the provider's default; suffix is inserted before the extension.
+// If the metadata cannot be fetched the title falls back to GetVideoTitle and the
+// remaining fields to placeholders.
+func ResolveOutputPath(videoURL, template string, provider Provider, ext, suffix string) (string, *VideoMetadata, error) {
+	if template == "" {
+		template = NameTemplateFor(provider.Name())
+	}
+
+	metadata, err := provider.FetchMetadata(videoURL)
+	if err != nil {
+		fmt.Printf("⚠️ Could not fetch metadata for %s, naming from title only: %v\n", videoURL, err)
+		metadata = &VideoMetadata{}
+		if title, err := GetVideoTitle(videoURL); err == nil {
+			metadata.Title = title
+		}
+	}
+
+	path, err := ReserveNamedOutputPath(template, metadata, ext, suffix)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, metadata, nil
+}
+
+// ReserveNamedOutputPath renders the template from metadata that is already known and
+// reserves the resulting path under ./output; suffix is inserted before the extension
+func ReserveNamedOutputPath(template string, metadata *VideoMetadata, ext, suffix string) (string, error) {
+	name, err := RenderNameTemplate(template, metadata, ext)
+	if err != nil {
+		return "", err
+	}
+	if suffix != "" {
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + suffix + filepath.Ext(name)
+	}
+	return ReserveOutputPath("./output", name)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderNameTemplate(t *testing.T) {
+	metadata := &VideoMetadata{
+		ID:         "abc123",
+		Title:      "Talk: Go/Rust? \"Live\"",
+		Uploader:   "",
+		UploaderID: "@chan",
+		UploadDate: "20240305",
+		Extractor:  "Youtube",
+	}
+	tests := []struct {
+		template string
+		ext      string
+		want     string
+		wantErr  bool
+	}{
+		{"{uploader}/{date}-{title}.{ext}", "mp4", "@chan/2024-03-05-Talk- Go-Rust- -Live-.mp4", false},
+		{"{provider}/{id}.{ext}", ".mkv", "youtube/abc123.mkv", false},
+		{"{ID}.{ext}", "mp4", "abc123.mp4", false},
+		{"{date}-{missing}.{ext}", "mp4", "", true},
+		{"../{id}.{ext}", "mp4", "", true},
+		{"/abs/{id}.{ext}", "mp4", "", true},
+	}
+	for _, tt := range tests {
+		got, err := RenderNameTemplate(tt.template, metadata, tt.ext)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("RenderNameTemplate(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("RenderNameTemplate(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestRenderNameTemplateKeepsValuesInOneComponent(t *testing.T) {
+	got, err := RenderNameTemplate("{title}.{ext}", &VideoMetadata{Title: "../../etc/passwd", UploadDate: "20240101"}, "mp4")
+	if err != nil {
+		t.Fatalf("RenderNameTemplate returned error: %v", err)
+	}
+	if strings.Contains(got, "/") || strings.HasPrefix(got, ".") {
+		t.Errorf("title escaped its path component: %q", got)
+	}
+}
+
+func TestNameFieldValue(t *testing.T) {
+	long := strings.Repeat("é", maxNameFieldRunes+20)
+	tests := []struct {
+		value, want string
+	}{
+		{"  Hello  ", "Hello"},
+		{"a/b:c", "a-b-c"},
+		{"..hidden..", "hidden"},
+		{"", "unknown"},
+		{"...", "unknown"},
+		{long, strings.Repeat("é", maxNameFieldRunes)},
+	}
+	for _, tt := range tests {
+		if got := nameFieldValue(tt.value); got != tt.want {
+			t.Errorf("nameFieldValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestReserveOutputPathSuffixesCollisions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "clip.mp4"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		path, err := ReserveOutputPath(dir, "clip.mp4")
+		if err != nil {
+			t.Fatalf("ReserveOutputPath returned error: %v", err)
+		}
+		defer ReleaseOutputPath(path)
+		paths = append(paths, filepath.Base(path))
+	}
+	want := []string{"clip (2).mp4", "clip (3).mp4", "clip (4).mp4"}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("reservation %d = %q, want %q", i+1, paths[i], want[i])
+		}
+	}
+
+	ReleaseOutputPath(filepath.Join(dir, "clip (2).mp4"))
+	path, err := ReserveOutputPath(dir, "clip.mp4")
+	if err != nil {
+		t.Fatalf("ReserveOutputPath returned error: %v", err)
+	}
+	defer ReleaseOutputPath(path)
+	if filepath.Base(path) != "clip (2).mp4" {
+		t.Errorf("released name was not reused, got %q", filepath.Base(path))
+	}
+}
+
+func TestReserveOutputPathCreatesDirectories(t *testing.T) {
+	dir := t.TempDir()
+	path, err := ReserveOutputPath(dir, "chan/2024-01-01-title.mp4")
+	if err != nil {
+		t.Fatalf("ReserveOutputPath returned error: %v", err)
+	}
+	defer ReleaseOutputPath(path)
+	if info, err := os.Stat(filepath.Join(dir, "chan")); err != nil || !info.IsDir() {
+		t.Errorf("parent directory was not created: %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PlaylistEntry is one video from yt-dlp's --flat-playlist output. Flat listings are fast
+// but sparse: date and duration are often missing and filled in on demand.
+type PlaylistEntry struct {
+	ID            string  `json:"id"`
+	Title         string  `json:"title"`
+	URL           string  `json:"url"`
+	WebpageURL    string  `json:"webpage_url"`
+	Duration      float64 `json:"duration"`
+	UploadDate    string  `json:"upload_date"` // YYYYMMDD
+	Timestamp     int64   `json:"timestamp"`
+	PlaylistID    string  `json:"playlist_id"`
+	PlaylistTitle string  `json:"playlist_title"`
+}
+
+// VideoURL returns the address to download the entry from
+func (e *PlaylistEntry) VideoURL() string {
+	if e.WebpageURL != "" {
+		return e.WebpageURL
+	}
+	return e.URL
+}
+
+// UploadTime returns when the entry was published, or the zero time if the listing didn't say
+func (e *PlaylistEntry) UploadTime() time.Time {
+	if e.Timestamp > 0 {
+		return time.Unix(e.Timestamp, 0).UTC()
+	}
+	return (&VideoMetadata{UploadDate: e.UploadDate}).UploadTime()
+}
+
+// PlaylistFilter selects which entries of a playlist or channel are downloaded
+type PlaylistFilter struct {
+	After       time.Time      // only entries published on or after this day
+	Before      time.Time      // only entries published before this day
+	Title       *regexp.Regexp // only entries whose title matches
+	MinDuration float64        // seconds; 0 for no minimum
+	MaxDuration float64        // seconds; 0 for no maximum
+	MaxCount    int            // stop after this many new entries; 0 for no limit
+	NewestFirst bool           // the listing is sorted newest first, as channel uploads are
+}
+
+// needsDetails reports whether the filter depends on fields flat listings may leave out
+func (f PlaylistFilter) needsDetails(e *PlaylistEntry) bool {
+	needsDate := (!f.After.IsZero() || !f.Before.IsZero()) && e.UploadTime().IsZero()
+	needsDuration := (f.MinDuration > 0 || f.MaxDuration > 0) && e.Duration == 0
+	return needsDate || needsDuration
+}
+
+// rejects returns why the filter drops an entry, or "" if it is kept. Fields the listing
+// left out don't reject anything.
+func (f PlaylistFilter) rejects(e *PlaylistEntry) string {
+	if f.Title != nil && !f.Title.MatchString(e.Title) {
+		return "title"
+	}
+	if uploaded := e.UploadTime(); !uploaded.IsZero() {
+		if !f.After.IsZero() && uploaded.Before(f.After) {
+			return "date"
+		}
+		if !f.Before.IsZero() && !uploaded.Before(f.Before) {
+			return "date"
+		}
+	}
+	if e.Duration > 0 {
+		if f.MinDuration > 0 && e.Duration < f.MinDuration {
+			return "duration"
+		}
+		if f.MaxDuration > 0 && e.Duration > f.MaxDuration {
+			return "duration"
+		}
+	}
+	return ""
+}
+
+// ChannelURL turns a channel handle like "@name" into its videos page and points channel
+// URLs at their uploads tab, since the bare channel page lists tabs rather than videos
+func ChannelURL(channel string) string {
+	if strings.HasPrefix(channel, "@") {
+		return "https://www.youtube.com/" + channel + "/videos"
+	}
+	parsed, err := url.Parse(channel)
+	if err != nil || ProviderFor(channel).Name() != "youtube" {
+		return channel
+	}
+	path := strings.TrimSuffix(parsed.Path, "/")
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	isChannel := len(segments) == 1 && strings.HasPrefix(segments[0], "@") ||
+		len(segments) == 2 && (segments[0] == "channel" || segments[0] == "c" || segments[0] == "user")
+	if isChannel {
+		parsed.Path = path + "/videos"
+	}
+	return parsed.String()
+}
+
+// ListPlaylist returns the entries of a playlist or channel in listing order.
+// extraArgs are passed to yt-dlp, e.g. --playlist-items to list only the newest entries.
+func ListPlaylist(listURL string, extraArgs ...string) ([]PlaylistEntry, error) {
+	args := append([]string{"--flat-playlist", "--dump-json"}, extraArgs...)
+	args = append(args, ytDLPAuthArgs(listURL)...)
+	cmd := exec.Command("yt-dlp", append(args, listURL)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %v\n%s", listURL, err, stderr.String())
+	}
+
+	var entries []PlaylistEntry
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry PlaylistEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse playlist entry: %v", err)
+		}
+		if entry.VideoURL() != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// PlaylistSummary describes one playlist or channel run. It is written to ./output/playlists
+// so there is a record of what each run fetched.
+type PlaylistSummary struct {
+	Source    string    `json:"source"`
+	Title     string    `json:"title"`
+	RunAt     time.Time `json:"runAt"`
+	Listed    int       `json:"listed"`
+	Archived  int       `json:"alreadyDownloaded"`
+	Filtered  int       `json:"filtered"`
+	Fetched   []string  `json:"fetched"`
+	Failed    []string  `json:"failed,omitempty"`
+	Remaining int       `json:"remainingOverMaxCount,omitempty"`
+}
+
+// DownloadPlaylist lists a playlist or channel, drops entries that are already in the archive
+// or fail the filter, downloads the rest through the download queue and writes a summary
+func DownloadPlaylist(listURL string, filter PlaylistFilter, opts DownloadQueueOptions) (*PlaylistSummary, []DownloadReport, error) {
+	fmt.Printf("📃 Listing %s...\n", listURL)
+	entries, err := ListPlaylist(listURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	summary := &PlaylistSummary{Source: listURL, RunAt: time.Now(), Listed: len(entries)}
+	if len(entries) > 0 {
+		summary.Title = entries[0].PlaylistTitle
+	}
+
+	var archive *DownloadArchive
+	if opts.ArchivePath != "" {
+		if archive, err = LoadDownloadArchive(opts.ArchivePath); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var urls []string
+	for i := range entries {
+		entry := &entries[i]
+		if filter.MaxCount > 0 && len(urls) >= filter.MaxCount {
+			// Stop before fetching details of entries that would not be downloaded anyway
+			summary.Remaining = len(entries) - i
+			break
+		}
+		if archive != nil && archive.Contains(opts.archiveKey(entry.VideoURL())) {
+			summary.Archived++
+			continue
+		}
+		// Filter on what the listing has first so details are only fetched for candidates
+		if filter.rejects(entry) == "" && filter.needsDetails(entry) {
+			if metadata, err := FetchVideoMetadata(entry.VideoURL()); err == nil {
+				entry.UploadDate, entry.Duration = metadata.UploadDate, metadata.Duration
+			} else {
+				fmt.Printf("⚠️ Could not fetch details of %s: %v\n", entry.VideoURL(), err)
+			}
+		}
+		if uploaded := entry.UploadTime(); filter.NewestFirst && !filter.After.IsZero() && !uploaded.IsZero() && uploaded.Before(filter.After) {
+			// Everything further down the listing is older still
+			summary.Filtered += len(entries) - i
+			break
+		}
+		if filter.rejects(entry) != "" {
+			summary.Filtered++
+			continue
+		}
+		urls = append(urls, entry.VideoURL())
+	}
+
+	fmt.Printf("📃 %d listed, %d already downloaded, %d filtered out, %d to download\n", summary.Listed, summary.Archived, summary.Filtered, len(urls))
+	if len(urls) == 0 {
+		return summary, nil, writePlaylistSummary(summary)
+	}
+
+	reports, err := RunDownloadQueue(urls, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, r := range reports {
+		switch {
+		case r.Err != nil:
+			summary.Failed = append(summary.Failed, r.URL)
+		case !r.Skipped:
+			summary.Fetched = append(summary.Fetched, r.OutputFile)
+		}
+	}
+	return summary, reports, writePlaylistSummary(summary)
+}
+
+// writePlaylistSummary saves the summary as ./output/playlists/<date>-<title>.json
+func writePlaylistSummary(summary *PlaylistSummary) error {
+	name := summary.Title
+	if name == "" {
+		name = summary.Source
+	}
+	path, err := ReserveOutputPath("./output/playlists", fmt.Sprintf("%s-%s.json", summary.RunAt.Format("2006-01-02_150405"), nameFieldValue(name)))
+	if err != nil {
+		return err
+	}
+	defer ReleaseOutputPath(path)
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write playlist summary: %v", err)
+	}
+	fmt.Printf("📝 Summary written to %s\n", filepath.Clean(path))
+	return nil
+}
+
+// ParseDay parses a YYYY-MM-DD date flag; an empty value gives the zero time
+func ParseDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+	}
+	return day, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// defaultPRPromptTemplate asks for the summary, changes and testing sections.
+// A custom template gets the same fields: .Branch, .Base, .Commits and .Changes.
+const defaultPRPromptTemplate = `Write a pull request description in GitHub-flavored Markdown for the branch "{{.Branch}}", which is being merged into "{{.Base}}".
+
+Use exactly these sections:
+## Summary
+One or two sentences on what the change does and why.
+## Changes
+A bullet list of the notable changes.
+## Testing
+How the change was or should be tested.
+
+Return ONLY the Markdown, without code fences or commentary.
+
+Commits:
+{{.Commits}}
+
+Changes:
+{{.Changes}}`
+
+// PRPromptData fills the pull request prompt template
+type PRPromptData struct {
+	Branch  string
+	Base    string
+	Commits string
+	Changes string
+}
+
+// GeneratePRDescription describes the current branch against its merge base with the default branch.
+// templatePath selects a custom prompt template; PR_PROMPT_TEMPLATE is used when it is empty.
+func GeneratePRDescription(templatePath string) (string, error) {
+	branch, err := CurrentBranch()
+	if err != nil {
+		return "", fmt.Errorf("error reading current branch: %v", err)
+	}
+	base, err := DefaultBranch()
+	if err != nil {
+		return "", err
+	}
+
+	mergeBase, err := runGit("merge-base", "HEAD", base)
+	if err != nil {
+		return "", fmt.Errorf("error finding merge base with %s: %v", base, err)
+	}
+
+	commits, err := runGit("log", "--reverse", "--format=- %s%n%w(0,2,2)%b", mergeBase+"..HEAD")
+	if err != nil {
+		return "", fmt.Errorf("error reading commit log: %v", err)
+	}
+	if commits == "" {
+		return "", fmt.Errorf("branch %s has no commits beyond %s", branch, base)
+	}
+
+	diff, err := runGit("diff", mergeBase+"..HEAD")
+	if err != nil {
+		return "", fmt.Errorf("error reading branch diff: %v", err)
+	}
+	changes, err := condenseDiff(diff)
+	if err != nil {
+		return "", err
+	}
+
+	promptText := defaultPRPromptTemplate
+	if templatePath == "" {
+		templatePath = os.Getenv("PR_PROMPT_TEMPLATE")
+	}
+	if templatePath != "" {
+		content, err := os.ReadFile(templatePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read prompt template: %v", err)
+		}
+		promptText = string(content)
+	}
+
+	tmpl, err := template.New("pr-description").Parse(promptText)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt template: %v", err)
+	}
+	var prompt bytes.Buffer
+	if err := tmpl.Execute(&prompt, PRPromptData{Branch: branch, Base: base, Commits: commits, Changes: changes}); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %v", err)
+	}
+
+	log.Printf("🤖 Generating pull request description for %s using GPT...", branch)
+	description, err := RequestChatCompletion([]GPTMessage{
+		{Role: "system", Content: "You write clear, reviewer-friendly pull request descriptions."},
+		{Role: "user", Content: prompt.String()},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate pull request description: %v", err)
+	}
+
+	return stripCodeFence(description), nil
+}
+
+// WritePRDescription prints the description to stdout, or saves it when outputPath is set
+func WritePRDescription(description, outputPath string) error {
+	if outputPath == "" {
+		fmt.Println(description)
+		return nil
+	}
+
+	if err := os.WriteFile(outputPath, []byte(strings.TrimSpace(description)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write pull request description: %v", err)
+	}
+	log.Printf("✅ Pull request description saved to %s", outputPath)
+	return nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EncodingPreset describes how a file is encoded for a particular destination
+type EncodingPreset struct {
+	Name         string  `json:"-"`            // set by GetPreset
+	VideoCodec   string  `json:"videoCodec"`   // ffmpeg encoder, e.g. libx264; empty drops the video
+	CRF          int     `json:"crf"`          // 0 leaves the encoder default
+	Speed        string  `json:"preset"`       // encoder speed preset, e.g. slow
+	AudioCodec   string  `json:"audioCodec"`   // ffmpeg encoder, e.g. aac
+	AudioBitrate string  `json:"audioBitrate"` // e.g. 128k; empty for lossless codecs
+	MaxHeight    int     `json:"maxHeight"`    // 0 keeps the source resolution
+	FrameRate    float64 `json:"frameRate"`    // 0 keeps the source frame rate
+	Vertical     bool    `json:"vertical"`     // center-crop to 9:16 at 1080x1920
+	Container    string  `json:"container"`    // output extension, e.g. mp4
+}
+
+// DefaultPresetName is used for downloads when no preset is given
+const DefaultPresetName = "premiere"
+
+// builtinPresets are always available; presets from ENCODING_PRESETS_FILE override them by name
+var builtinPresets = map[string]EncodingPreset{
+	"premiere":        {VideoCodec: "libx264", CRF: 23, Speed: "slow", AudioCodec: "aac", AudioBitrate: "128k", Container: "mp4"},
+	"web":             {VideoCodec: "libx264", CRF: 26, Speed: "medium", AudioCodec: "aac", AudioBitrate: "128k", MaxHeight: 1080, Container: "mp4"},
+	"archive":         {VideoCodec: "libx265", CRF: 18, Speed: "slow", AudioCodec: "flac", Container: "mkv"},
+	"shorts-vertical": {VideoCodec: "libx264", CRF: 21, Speed: "medium", AudioCodec: "aac", AudioBitrate: "160k", FrameRate: 30, Vertical: true, Container: "mp4"},
+	"audio-only":      {AudioCodec: "aac", AudioBitrate: "192k", Container: "m4a"},
+}
+
+// encoderCodecs maps ffmpeg encoders to the codec names ffprobe reports for their output
+var encoderCodecs = map[string]string{
+	"libx264":    "h264",
+	"libx265":    "hevc",
+	"libvpx-vp9": "vp9",
+	"libaom-av1": "av1",
+	"libsvtav1":  "av1",
+	"aac":        "aac",
+	"libopus":    "opus",
+	"libmp3lame": "mp3",
+	"flac":       "flac",
+}
+
+// presetContainers are the output extensions a preset may write
+var presetContainers = []string{"mp4", "mkv", "mov", "webm", "m4a", "mp3", "flac", "ogg", "opus", "wav"}
+
+// validate rejects presets that would only fail once ffmpeg runs
+func (p *EncodingPreset) validate() error {
+	if p.Container == "" {
+		return fmt.Errorf("has no container")
+	}
+	known := false
+	for _, container := range presetContainers {
+		known = known || p.Container == container
+	}
+	if !known {
+		return fmt.Errorf("has unknown container %q (available: %s)", p.Container, strings.Join(presetContainers, ", "))
+	}
+	if p.AudioCodec == "" {
+		return fmt.Errorf("has no audioCodec")
+	}
+	if p.CRF < 0 {
+		return fmt.Errorf("has negative crf %d", p.CRF)
+	}
+	if p.FrameRate < 0 {
+		return fmt.Errorf("has negative frameRate %g", p.FrameRate)
+	}
+	if p.MaxHeight < 0 {
+		return fmt.Errorf("has negative maxHeight %d", p.MaxHeight)
+	}
+	return nil
+}
+
+// LoadPresets returns the built-in presets merged with the ones defined in
+// ENCODING_PRESETS_FILE (default ./input/presets.json), a JSON object of name to preset
+func LoadPresets() (map[string]EncodingPreset, error) {
+	presets := map[string]EncodingPreset{}
+	for name, preset := range builtinPresets {
+		presets[name] = preset
+	}
+
+	presetsFile := os.Getenv("ENCODING_PRESETS_FILE")
+	if presetsFile == "" {
+		presetsFile = "./input/presets.json"
+	}
+	data, err := os.ReadFile(presetsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return presets, nil
+		}
+		return nil, fmt.Errorf("failed to read presets file: %v", err)
+	}
+
+	var custom map[string]EncodingPreset
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("failed to parse presets file %s: %v", presetsFile, err)
+	}
+	for name, preset := range custom {
+		if err := preset.validate(); err != nil {
+			return nil, fmt.Errorf("preset %q in %s %v", name, presetsFile, err)
+		}
+		presets[name] = preset
+	}
+	return presets, nil
+}
+
+// GetPreset looks up a preset by name
+func GetPreset(name string) (*EncodingPreset, error) {
+	presets, err := LoadPresets()
+	if err != nil {
+		return nil, err
+	}
+
+	preset, ok := presets[name]
+	if !ok {
+		var names []string
+		for n := range presets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	preset.Name = name
+	return &preset, nil
+}
+
+// needsVideoEncode reports whether the probed video stream can't simply be copied into this preset
+func (p *EncodingPreset) needsVideoEncode(info *MediaInfo) bool {
+	if info.VideoCodec != encoderCodecs[p.VideoCodec] {
+		return true
+	}
+	// Premiere and most players reject H.264/HEVC with chroma other than 4:2:0
+	if info.PixFmt != "" && info.PixFmt != "yuv420p" && info.PixFmt != "yuvj420p" {
+		return true
+	}
+	if p.MaxHeight > 0 && info.Height > p.MaxHeight {
+		return true
+	}
+	if p.FrameRate > 0 && info.FrameRate != p.FrameRate {
+		return true
+	}
+	return p.Vertical && (info.Width != 1080 || info.Height != 1920)
+}
+
+// Plan picks the cheapest way to turn the probed file into this preset's output.
+// Without probe information everything is re-encoded.
+func (p *EncodingPreset) Plan(info *MediaInfo) TranscodePath {
+	if info == nil {
+		return TranscodeFull
+	}
+	if p.VideoCodec != "" && p.needsVideoEncode(info) {
+		return TranscodeFull
+	}
+	if info.AudioCodec != "" && info.AudioCodec != encoderCodecs[p.AudioCodec] {
+		return TranscodeAudio
+	}
+	return TranscodeRemux
+}
+
+// CodecArgs returns the ffmpeg output options that implement the preset along the given path
+func (p *EncodingPreset) CodecArgs(path TranscodePath) []string {
+	var args []string
+
+	switch {
+	case p.VideoCodec == "":
+		args = append(args, "-vn")
+	case path != TranscodeFull:
+		args = append(args, "-c:v", "copy")
+	default:
+		args = append(args, "-c:v", p.VideoCodec)
+		if p.CRF > 0 {
+			args = append(args, "-crf", strconv.Itoa(p.CRF))
+		}
+		if p.Speed != "" {
+			args = append(args, "-preset", p.Speed)
+		}
+
+		var filters []string
+		if p.Vertical {
+			filters = append(filters, "crop='min(iw,ih*9/16)':'min(ih,iw*16/9)'", "scale=1080:1920")
+		} else if p.MaxHeight > 0 {
+			filters = append(filters, fmt.Sprintf("scale=-2:'min(ih,%d)'", p.MaxHeight))
+		}
+		if len(filters) > 0 {
+			args = append(args, "-vf", strings.Join(filters, ","))
+		}
+		if p.FrameRate > 0 {
+			args = append(args, "-r", strconv.FormatFloat(p.FrameRate, 'f', -1, 64))
+		}
+		if p.VideoCodec == "libx264" || p.VideoCodec == "libx265" {
+			args = append(args, "-pix_fmt", "yuv420p")
+		}
+	}
+
+	if path == TranscodeRemux {
+		args = append(args, "-c:a", "copy")
+	} else {
+		args = append(args, "-c:a", p.AudioCodec)
+		if p.AudioBitrate != "" {
+			args = append(args, "-b:a", p.AudioBitrate)
+		}
+	}
+
+	switch p.Container {
+	case "mp4", "m4a", "mov":
+		args = append(args, "-movflags", "+faststart")
+	}
+	return args
+}
+
+// TranscodeWithPreset converts input into output using the preset, copying streams where possible.
+// A nil preset copies every stream. When timeRange is set only that section is kept: re-encodes
+// seek after decoding so the cut is frame-accurate, stream copies seek on the input and snap to
+// the nearest keyframe. It returns the path taken and the probe of the input (nil if probing failed).
+func TranscodeWithPreset(input, output string, preset *EncodingPreset, timeRange *TimeRange) (TranscodePath, *MediaInfo, error) {
+	info, err := ProbeMedia(input)
+	if err != nil && preset != nil {
+		fmt.Printf("⚠️ Could not probe %s, falling back to a full transcode: %v\n", input, err)
+	}
+
+	path := TranscodeRemux
+	codecArgs := []string{"-c", "copy"}
+	if preset != nil {
+		path = preset.Plan(info)
+		codecArgs = preset.CodecArgs(path)
+	}
+	fmt.Printf("🎞️ Processing path: %s\n", path)
+
+	args := []string{"-y"}
+	if timeRange != nil && path != TranscodeFull {
+		args = append(args, timeRange.FFmpegArgs()...)
+	}
+	args = append(args, "-i", input)
+	if timeRange != nil && path == TranscodeFull {
+		args = append(args, timeRange.FFmpegArgs()...)
+	}
+	args = append(args, codecArgs...)
+	args = append(args, output)
+
+	cmd := exec.Command("ffmpeg", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return path, info, fmt.Errorf("error processing video (%s): %v", path, err)
+	}
+	return path, info, nil
+}
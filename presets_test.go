@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPresetPlan(t *testing.T) {
+	premiere := builtinPresets["premiere"]
+	web := builtinPresets["web"]
+	audioOnly := builtinPresets["audio-only"]
+	shorts := builtinPresets["shorts-vertical"]
+
+	h264 := MediaInfo{VideoCodec: "h264", AudioCodec: "aac", PixFmt: "yuv420p", Width: 1920, Height: 1080, FrameRate: 30}
+	with := func(change func(*MediaInfo)) *MediaInfo {
+		info := h264
+		change(&info)
+		return &info
+	}
+	tests := []struct {
+		name   string
+		preset EncodingPreset
+		info   *MediaInfo
+		want   TranscodePath
+	}{
+		{"unprobed input is fully transcoded", premiere, nil, TranscodeFull},
+		{"matching streams are copied", premiere, &h264, TranscodeRemux},
+		{"other audio codec", premiere, with(func(i *MediaInfo) { i.AudioCodec = "opus" }), TranscodeAudio},
+		{"no audio stream", premiere, with(func(i *MediaInfo) { i.AudioCodec = "" }), TranscodeRemux},
+		{"other video codec", premiere, with(func(i *MediaInfo) { i.VideoCodec = "vp9" }), TranscodeFull},
+		{"10-bit chroma", premiere, with(func(i *MediaInfo) { i.PixFmt = "yuv422p10le" }), TranscodeFull},
+		{"taller than the maximum", web, with(func(i *MediaInfo) { i.Height = 2160 }), TranscodeFull},
+		{"within the maximum", web, &h264, TranscodeRemux},
+		{"frame rate differs", shorts, with(func(i *MediaInfo) { i.Width, i.Height = 1080, 1920; i.FrameRate = 60 }), TranscodeFull},
+		{"already vertical", shorts, with(func(i *MediaInfo) { i.Width, i.Height = 1080, 1920 }), TranscodeRemux},
+		{"landscape into vertical", shorts, &h264, TranscodeFull},
+		{"audio-only ignores the video", audioOnly, with(func(i *MediaInfo) { i.VideoCodec = "vp9" }), TranscodeRemux},
+	}
+	for _, tt := range tests {
+		if got := tt.preset.Plan(tt.info); got != tt.want {
+			t.Errorf("%s: Plan() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPresetCodecArgs(t *testing.T) {
+	web := builtinPresets["web"]
+	tests := []struct {
+		name   string
+		preset EncodingPreset
+		path   TranscodePath
+		want   []string
+	}{
+		{"remux", web, TranscodeRemux, []string{"-c:v", "copy", "-c:a", "copy", "-movflags", "+faststart"}},
+		{"audio only", web, TranscodeAudio, []string{"-c:v", "copy", "-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart"}},
+		{"full", web, TranscodeFull, []string{
+			"-c:v", "libx264", "-crf", "26", "-preset", "medium", "-vf", "scale=-2:'min(ih,1080)'", "-pix_fmt", "yuv420p",
+			"-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart",
+		}},
+		{"archive", builtinPresets["archive"], TranscodeFull, []string{
+			"-c:v", "libx265", "-crf", "18", "-preset", "slow", "-pix_fmt", "yuv420p", "-c:a", "flac",
+		}},
+		{"drops video", builtinPresets["audio-only"], TranscodeFull, []string{"-vn", "-c:a", "aac", "-b:a", "192k", "-movflags", "+faststart"}},
+	}
+	for _, tt := range tests {
+		if got := tt.preset.CodecArgs(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: CodecArgs() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPresetValidate(t *testing.T) {
+	valid := EncodingPreset{VideoCodec: "libx264", CRF: 23, AudioCodec: "aac", Container: "mp4"}
+	tests := []struct {
+		name    string
+		change  func(*EncodingPreset)
+		wantErr bool
+	}{
+		{"valid", func(p *EncodingPreset) {}, false},
+		{"audio only", func(p *EncodingPreset) { p.VideoCodec, p.Container = "", "m4a" }, false},
+		{"no container", func(p *EncodingPreset) { p.Container = "" }, true},
+		{"unknown container", func(p *EncodingPreset) { p.Container = "avi" }, true},
+		{"no audio codec", func(p *EncodingPreset) { p.AudioCodec = "" }, true},
+		{"negative crf", func(p *EncodingPreset) { p.CRF = -1 }, true},
+		{"negative frame rate", func(p *EncodingPreset) { p.FrameRate = -30 }, true},
+		{"negative max height", func(p *EncodingPreset) { p.MaxHeight = -720 }, true},
+	}
+	for _, tt := range tests {
+		preset := valid
+		tt.change(&preset)
+		if err := preset.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+	for name, preset := range builtinPresets {
+		if err := preset.validate(); err != nil {
+			t.Errorf("built-in preset %q %v", name, err)
+		}
+	}
+}
+
+func TestLoadPresetsRejectsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "presets.json")
+	t.Setenv("ENCODING_PRESETS_FILE", path)
+
+	if err := os.WriteFile(path, []byte(`{"premiere": {"audioCodec": "aac", "crf": 30, "container": "mp4"}, "tiny": {"audioCodec": "libopus", "container": "opus"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	presets, err := LoadPresets()
+	if err != nil {
+		t.Fatalf("LoadPresets returned error: %v", err)
+	}
+	if presets["premiere"].CRF != 30 || presets["tiny"].Container != "opus" || presets["web"].Container != "mp4" {
+		t.Errorf("custom presets were not merged over the built-in ones: %+v", presets)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"bad": {"audioCodec": "aac", "container": "avi"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPresets(); err == nil {
+		t.Error("LoadPresets accepted a preset with an unknown container")
+	}
+}
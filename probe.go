@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MediaInfo summarizes the first video and audio streams of a media file
+type MediaInfo struct {
+	Container  string  `json:"container"`
+	Duration   float64 `json:"duration"`
+	VideoCodec string  `json:"videoCodec,omitempty"`
+	AudioCodec string  `json:"audioCodec,omitempty"`
+	PixFmt     string  `json:"pixFmt,omitempty"`
+	FrameRate  float64 `json:"frameRate,omitempty"`
+	Width      int     `json:"width,omitempty"`
+	Height     int     `json:"height,omitempty"`
+}
+
+// ffprobeOutput mirrors the parts of `ffprobe -show_format -show_streams -of json` we read
+type ffprobeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		CodecName string `json:"codec_name"`
+		PixFmt    string `json:"pix_fmt"`
+		FrameRate string `json:"avg_frame_rate"` // e.g. "30000/1001"
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+	} `json:"streams"`
+}
+
+// ProbeMedia reads container, duration and stream codecs of a file with ffprobe
+func ProbeMedia(path string) (*MediaInfo, error) {
+	cmd := exec.Command("ffprobe", "-v", "error", "-show_format", "-show_streams", "-of", "json", path)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error probing %s: %v", path, err)
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %v", err)
+	}
+
+	info := &MediaInfo{Container: probe.Format.FormatName}
+	info.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	for _, stream := range probe.Streams {
+		switch {
+		case stream.CodecType == "video" && info.VideoCodec == "":
+			info.VideoCodec = stream.CodecName
+			info.PixFmt = stream.PixFmt
+			info.FrameRate = parseFrameRate(stream.FrameRate)
+			info.Width = stream.Width
+			info.Height = stream.Height
+		case stream.CodecType == "audio" && info.AudioCodec == "":
+			info.AudioCodec = stream.CodecName
+		}
+	}
+	return info, nil
+}
+
+// TranscodePath is how a file gets from its source codecs to an encoding preset's codecs
+type TranscodePath string
+
+const (
+	TranscodeRemux TranscodePath = "remux only"           // both streams already compatible, copy them
+	TranscodeAudio TranscodePath = "audio-only transcode" // copy the video, re-encode the audio
+	TranscodeFull  TranscodePath = "full transcode"       // re-encode both streams
+)
+
+// parseFrameRate turns ffprobe's "num/den" rate into frames per second, rounded to 3 decimals
+func parseFrameRate(rate string) float64 {
+	num, den, found := strings.Cut(rate, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return math.Round(n/d*1000) / 1000
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Provider is a source the download command can fetch videos from
+type Provider interface {
+	// Name is the provider's config key, used for name templates (DOWNLOAD_TEMPLATE_<NAME>)
+	// and cookie files (COOKIES_FILE_<NAME>)
+	Name() string
+	// Matches reports whether the provider handles the URL
+	Matches(u *url.URL) bool
+	// Format is the yt-dlp format selector the provider downloads with
+	Format() string
+	// AudioFormat is the yt-dlp format selector for audio-only downloads
+	AudioFormat() string
+	// FetchMetadata looks up the video without downloading it
+	FetchMetadata(videoURL string) (*VideoMetadata, error)
+	// Download saves the video under ./output and returns its path
+	Download(videoURL string, opts DownloadOptions) (string, error)
+}
+
+// ytDLPProvider downloads from a site yt-dlp has an extractor for
+type ytDLPProvider struct {
+	name          string
+	hosts         []string // matched exactly or as a parent domain
+	format        string
+	audioFormat   string // empty uses bestaudio/best
+	extraArgs     []string
+	defaultPreset bool // convert with DefaultPresetName when no preset is given; otherwise keep the download as is
+}
+
+func (p *ytDLPProvider) Name() string { return p.name }
+
+func (p *ytDLPProvider) Format() string { return p.format }
+
+func (p *ytDLPProvider) AudioFormat() string {
+	if p.audioFormat == "" {
+		return "bestaudio/best"
+	}
+	return p.audioFormat
+}
+
+func (p *ytDLPProvider) Matches(u *url.URL) bool {
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	for _, h := range p.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ytDLPProvider) FetchMetadata(videoURL string) (*VideoMetadata, error) {
+	return FetchVideoMetadata(videoURL)
+}
+
+// Download fetches the video with yt-dlp and converts it with the chosen preset, copying the
+// streams that already match it. Without a preset the download is kept as is unless the
+// provider converts with DefaultPresetName by default; a time range is then stream-copied.
+// The output is named from the name template (or the provider's default).
+func (p *ytDLPProvider) Download(videoURL string, opts DownloadOptions) (string, error) {
+	EnsureOutputDir("output")
+
+	preset := opts.Preset
+	if preset == nil && p.defaultPreset {
+		var err error
+		if preset, err = GetPreset(DefaultPresetName); err != nil {
+			return "", err
+		}
+	}
+	ext := "mp4"
+	if preset != nil {
+		ext = preset.Container
+	}
+
+	outputFile, metadata, err := ResolveOutputPath(videoURL, opts.NameTemplate, p, ext, opts.nameSuffix())
+	if err != nil {
+		return "", fmt.Errorf("error naming output file: %v", err)
+	}
+	defer ReleaseOutputPath(outputFile)
+
+	downloadFile := outputFile
+	if preset != nil || opts.TimeRange != nil {
+		downloadFile = fmt.Sprintf("./output/%s_temp_%s_video.mp4", uuid.New().String(), p.name)
+	}
+
+	fmt.Printf("Downloading video from %s: %s\n", p.name, videoURL)
+	extraArgs := p.extraArgs
+	if preset != nil && opts.TimeRange != nil {
+		// yt-dlp cuts sections on keyframes; when the preset re-encodes anyway, have it cut
+		// exactly at the requested times instead of up to a GOP away
+		extraArgs = append(append([]string{}, p.extraArgs...), "--force-keyframes-at-cuts")
+	}
+	trimmed, err := runYTDLP(videoURL, p.format, downloadFile, opts.TimeRange, extraArgs...)
+	if err != nil {
+		return "", fmt.Errorf("error downloading video from %s: %v", p.name, err)
+	}
+	if _, err := os.Stat(downloadFile); err != nil {
+		return "", fmt.Errorf("downloaded video file not found: %v", err)
+	}
+
+	var original *MediaInfo
+	processing := ""
+	if downloadFile != outputFile {
+		// Trim here only if yt-dlp could not fetch just the section
+		trimRange := opts.TimeRange
+		if trimmed {
+			trimRange = nil
+		}
+		var transcodePath TranscodePath
+		transcodePath, original, err = TranscodeWithPreset(downloadFile, outputFile, preset, trimRange)
+		if err != nil {
+			return "", err
+		}
+		processing = fmt.Sprintf(" (%s)", transcodePath)
+
+		if err := CleanUpFiles(downloadFile); err != nil {
+			return "", fmt.Errorf("error cleaning up temporary files: %v", err)
+		}
+	}
+
+	if err := WriteSidecar(outputFile, videoURL, metadata, original); err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+	}
+
+	opts.downloadCaptionsFor(videoURL, outputFile)
+
+	fmt.Printf("Video downloaded and saved as %s%s\n", outputFile, processing)
+	return outputFile, nil
+}
+
+// genericProvider handles every other URL: direct media files and HLS playlists are fetched
+// natively, anything else is left to yt-dlp's generic extractor
+type genericProvider struct {
+	ytDLPProvider
+}
+
+func (p *genericProvider) Matches(u *url.URL) bool { return true }
+
+func (p *genericProvider) FetchMetadata(videoURL string) (*VideoMetadata, error) {
+	if isDirectMediaURL(videoURL) {
+		return directMetadata(videoURL), nil
+	}
+	return FetchVideoMetadata(videoURL)
+}
+
+func (p *genericProvider) Download(videoURL string, opts DownloadOptions) (string, error) {
+	if isDirectMediaURL(videoURL) {
+		return DownloadDirect(videoURL, opts)
+	}
+	return p.ytDLPProvider.Download(videoURL, opts)
+}
+
+// providers are tried in order; the generic provider catches everything else
+var providers = []Provider{
+	&ytDLPProvider{
+		name:          "youtube",
+		hosts:         []string{"youtube.com", "youtu.be", "youtube-nocookie.com"},
+		format:        "bestvideo[ext=mp4]+bestaudio[ext=m4a]",
+		audioFormat:   "bestaudio[ext=m4a]/bestaudio",
+		extraArgs:     []string{"-N", "16"},
+		defaultPreset: true,
+	},
+	&ytDLPProvider{
+		name:   "x",
+		hosts:  []string{"x.com", "twitter.com"},
+		format: "best",
+	},
+	&ytDLPProvider{
+		name:   "bluesky",
+		hosts:  []string{"bsky.app"},
+		format: "best",
+	},
+	&ytDLPProvider{
+		// Reddit serves video and audio as separate DASH streams
+		name:      "reddit",
+		hosts:     []string{"reddit.com", "redd.it"},
+		format:    "bestvideo+bestaudio/best",
+		extraArgs: []string{"--merge-output-format", "mp4"},
+	},
+}
+
+var defaultProvider Provider = &genericProvider{ytDLPProvider{
+	name:          "generic",
+	format:        "bestvideo+bestaudio/best",
+	extraArgs:     []string{"--merge-output-format", "mp4"},
+	defaultPreset: true,
+}}
+
+// ProviderFor returns the provider that handles the URL
+func ProviderFor(videoURL string) Provider {
+	parsed, err := url.Parse(videoURL)
+	if err != nil {
+		return defaultProvider
+	}
+	for _, p := range providers {
+		if p.Matches(parsed) {
+			return p
+		}
+	}
+	return defaultProvider
+}
+
+// GetProvider looks up a provider by name
+func GetProvider(name string) (Provider, error) {
+	all := append([]Provider{defaultProvider}, providers...)
+
+	var names []string
+	for _, p := range all {
+		if p.Name() == strings.ToLower(name) {
+			return p, nil
+		}
+		names = append(names, p.Name())
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown provider %q (available: %s)", name, strings.Join(names, ", "))
+}
@@ -70,39 +70,71 @@ func GenerateTitlesAndDescriptions(videoFile string) (*GPTResponse, error) {
 		return nil, fmt.Errorf("failed to read transcription file: %v", err)
 	}
 
+	// Construct the chat messages for OpenAI
+	messages := []GPTMessage{
+		{Role: "system", Content: "You generate video titles and a single description in JSON format."},
+		{Role: "user", Content: fmt.Sprintf(
+			"Based on this transcript, generate exactly 5 possible video titles that evoke emotion and curiosity and ONE single detailed description.\n\n"+
+				"Return ONLY valid JSON. Example:\n"+
+				"```json\n"+
+				"{ \"titles\": [\"Title 1\", \"Title 2\", \"Title 3\", \"Title 4\", \"Title 5\"],"+
+				" \"description\": \"This is the only detailed description provided.\" }"+
+				"\n```"+
+				"\n\nStrictly follow this format. DO NOT include anything else."+ 
+				"\n\nTranscript:\n%s", string(transcriptionText))},
+	}
+
+	gptText, err := RequestChatCompletion(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	// Debugging: Print raw GPT output
+	fmt.Println("🔍 GPT Raw Response:")
+	fmt.Println(gptText)
+
+	// Remove possible triple backticks
+	gptText = strings.TrimSpace(gptText)
+	gptText = strings.TrimPrefix(gptText, "```json")
+	gptText = strings.TrimSuffix(gptText, "```")
+
+	// Parse GPT-generated JSON
+	var gptResponse GPTResponse
+	err = json.Unmarshal([]byte(gptText), &gptResponse)
+	if err != nil {
+		log.Printf("Error parsing GPT JSON response: %v\nRaw Output: %s", err, gptText)
+		return nil, fmt.Errorf("failed to parse GPT JSON response: %v", err)
+	}
+
+	return &gptResponse, nil
+}
+
+
+// RequestChatCompletion sends messages to the OpenAI chat completions API and returns the first reply.
+// The model defaults to gpt-4 and can be overridden with OPENAI_MODEL.
+func RequestChatCompletion(messages []GPTMessage) (string, error) {
 	// Get API key from environment
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
-		return nil, fmt.Errorf("missing OPENAI_API_KEY environment variable")
-	}
-
-	// Construct OpenAI API request
-	requestBody := OpenAIRequest{
-		Model: "gpt-4",
-		Messages: []GPTMessage{
-			{Role: "system", Content: "You generate video titles and a single description in JSON format."},
-			{Role: "user", Content: fmt.Sprintf(
-				"Based on this transcript, generate exactly 5 possible video titles that evoke emotion and curiosity and ONE single detailed description.\n\n"+
-					"Return ONLY valid JSON. Example:\n"+
-					"```json\n"+
-					"{ \"titles\": [\"Title 1\", \"Title 2\", \"Title 3\", \"Title 4\", \"Title 5\"],"+
-					" \"description\": \"This is the only detailed description provided.\" }"+
-					"\n```"+
-					"\n\nStrictly follow this format. DO NOT include anything else."+ 
-					"\n\nTranscript:\n%s", string(transcriptionText))},
-		},
+		return "", fmt.Errorf("missing OPENAI_API_KEY environment variable")
+	}
+
+	model := os.Getenv("OPENAI_MODEL")
+	if model == "" {
+		model = "gpt-4"
 	}
 
 	// Convert requestBody to JSON
+	requestBody := OpenAIRequest{Model: model, Messages: messages}
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode OpenAI request: %v", err)
+		return "", fmt.Errorf("failed to encode OpenAI request: %v", err)
 	}
 
 	// Send request to OpenAI API
 	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create OpenAI request: %v", err)
+		return "", fmt.Errorf("failed to create OpenAI request: %v", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
@@ -110,14 +142,18 @@ func GenerateTitlesAndDescriptions(videoFile string) (*GPTResponse, error) {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send OpenAI request: %v", err)
+		return "", fmt.Errorf("failed to send OpenAI request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read OpenAI response: %v", err)
+		return "", fmt.Errorf("failed to read OpenAI response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OpenAI API error: %d %s\nResponse: %s", resp.StatusCode, http.StatusText(resp.StatusCode), string(body))
 	}
 
 	// Extract content from GPT response
@@ -125,36 +161,16 @@ func GenerateTitlesAndDescriptions(videoFile string) (*GPTResponse, error) {
 	err = json.Unmarshal(body, &apiResponse)
 	if err != nil {
 		log.Printf("Error parsing GPT response: %v\nRaw Output: %s", err, string(body))
-		return nil, fmt.Errorf("failed to parse GPT response: %v", err)
+		return "", fmt.Errorf("failed to parse GPT response: %v", err)
 	}
 
 	// Extract GPT response text
 	if len(apiResponse.Choices) == 0 {
-		return nil, fmt.Errorf("no choices returned from GPT")
-	}
-	gptText := apiResponse.Choices[0].Message.Content
-
-	// Debugging: Print raw GPT output
-	fmt.Println("🔍 GPT Raw Response:")
-	fmt.Println(gptText)
-
-	// Remove possible triple backticks
-	gptText = strings.TrimSpace(gptText)
-	gptText = strings.TrimPrefix(gptText, "```json")
-	gptText = strings.TrimSuffix(gptText, "```")
-
-	// Parse GPT-generated JSON
-	var gptResponse GPTResponse
-	err = json.Unmarshal([]byte(gptText), &gptResponse)
-	if err != nil {
-		log.Printf("Error parsing GPT JSON response: %v\nRaw Output: %s", err, gptText)
-		return nil, fmt.Errorf("failed to parse GPT JSON response: %v", err)
+		return "", fmt.Errorf("no choices returned from GPT")
 	}
-
-	return &gptResponse, nil
+	return apiResponse.Choices[0].Message.Content, nil
 }
 
-
 // Utility function to remove file extension
 func stripFileExtension(filename string) string {
 	for i := len(filename) - 1; i >= 0; i-- {
@@ -165,9 +181,11 @@ func stripFileExtension(filename string) string {
 	return filename
 }
 
-func PublishWithAutoGeneratedMetadata(videoPath, hashtags, platforms, thumbnailPath string) error {
-	// Step 1: Transcribe the audio (No return value)
-	TranscribeAudio(videoPath)
+func PublishWithAutoGeneratedMetadata(videoPath, hashtags, platforms, thumbnailPath string, transcription TranscriptionOptions) error {
+	// Step 1: Transcribe the audio; titles can't be generated without a transcript
+	if _, err := TranscribeAudio(videoPath, transcription); err != nil {
+		return err
+	}
 
 	// Step 2: Generate title and description using GPT
 	fmt.Println("🤖 Generating possible titles and descriptions using GPT...")
@@ -216,9 +234,11 @@ func PublishWithAutoGeneratedMetadata(videoPath, hashtags, platforms, thumbnailP
 	return nil
 }
 
-func GenerateScript(videoPath, inputScriptPath string) error {
-	// Step 1: Transcribe the audio (No return value)
-	TranscribeAudio(videoPath)
+func GenerateScript(videoPath, inputScriptPath string, transcription TranscriptionOptions) error {
+	// Step 1: Transcribe the audio; titles can't be generated without a transcript
+	if _, err := TranscribeAudio(videoPath, transcription); err != nil {
+		return err
+	}
 
 	// Step 2: Generate title and description using GPT
 	fmt.Println("🤖 Generating possible titles and descriptions using GPT...")
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecordOptions configures RecordStream
+type RecordOptions struct {
+	SegmentLength time.Duration // length of each rolling segment file
+	MaxDuration   time.Duration // stop after recording this long; 0 records until the stream ends
+	MaxReconnects int           // consecutive failed reconnects before giving up
+	KeepSegments  bool          // keep the segment files after stitching
+	NameTemplate  string        // output name template; empty uses the provider default
+}
+
+// liveStreamInputs returns the media URLs ffmpeg should read: the URL itself for an HLS
+// playlist, otherwise what yt-dlp resolves (one muxed stream, or separate video and audio).
+// yt-dlp URLs are signed and expire, so they are resolved again on every reconnect.
+func liveStreamInputs(streamURL string) ([]string, error) {
+	if isHLSURL(streamURL) {
+		return []string{streamURL}, nil
+	}
+	args := append([]string{"-g", "-f", "best/bestvideo+bestaudio"}, ytDLPAuthArgs(streamURL)...)
+	output, err := exec.Command("yt-dlp", append(args, streamURL)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve stream: %v", err)
+	}
+	inputs := strings.Fields(string(output))
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("yt-dlp returned no stream URL")
+	}
+	return inputs, nil
+}
+
+// streamIsLive reports whether the stream is still broadcasting
+func streamIsLive(streamURL string) (bool, error) {
+	if isHLSURL(streamURL) {
+		client := &http.Client{}
+		playlist, err := FetchHLSPlaylist(client, streamURL)
+		if err != nil {
+			return false, err
+		}
+		if playlist.IsMaster() {
+			if playlist, err = FetchHLSPlaylist(client, SelectHLSVariant(playlist.Variants, 0).URI); err != nil {
+				return false, err
+			}
+		}
+		return !playlist.Ended, nil
+	}
+
+	args := append([]string{"--print", "live_status", "--no-warnings"}, ytDLPAuthArgs(streamURL)...)
+	output, err := exec.Command("yt-dlp", append(args, streamURL)...).Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to check live status: %v", err)
+	}
+	return strings.TrimSpace(string(output)) == "is_live", nil
+}
+
+// recordSession runs one ffmpeg connection, writing segments named part_<session>_<n>.ts.
+// It returns when the connection drops, the length limit is reached or stop is closed.
+func recordSession(inputs []string, segmentDir string, session int, segmentLength, limit time.Duration, stop <-chan struct{}) error {
+	args := []string{"-hide_banner", "-loglevel", "warning", "-stats"}
+	for _, input := range inputs {
+		// Give up on a stalled connection after 15s so the session can be reconnected
+		args = append(args, "-reconnect", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "30", "-rw_timeout", "15000000", "-i", input)
+	}
+	if len(inputs) > 1 {
+		args = append(args, "-map", "0:v", "-map", "1:a")
+	} else {
+		args = append(args, "-map", "0")
+	}
+	if limit > 0 {
+		args = append(args, "-t", strconv.FormatFloat(limit.Seconds(), 'f', 3, 64))
+	}
+	args = append(args,
+		"-c", "copy",
+		"-f", "segment",
+		"-segment_time", strconv.FormatFloat(segmentLength.Seconds(), 'f', 3, 64),
+		"-segment_format", "mpegts",
+		"-reset_timestamps", "1",
+		filepath.Join(segmentDir, fmt.Sprintf("part_%03d_%%05d.ts", session)),
+	)
+
+	cmd := exec.Command("ffmpeg", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %v", err)
+	}
+
+	// Let ffmpeg close the current segment cleanly when recording is stopped
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			cmd.Process.Signal(os.Interrupt)
+		case <-done:
+		}
+	}()
+	return cmd.Wait()
+}
+
+// listSegments returns the recorded segment files in recording order
+func listSegments(segmentDir string) ([]string, error) {
+	segments, err := filepath.Glob(filepath.Join(segmentDir, "part_*.ts"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(segments)
+
+	// Drop empty files left by a connection that failed immediately
+	var result []string
+	for _, segment := range segments {
+		if stat, err := os.Stat(segment); err == nil && stat.Size() > 0 {
+			result = append(result, segment)
+		}
+	}
+	return result, nil
+}
+
+// StitchSegments joins the segments into one MP4 without re-encoding
+func StitchSegments(segments []string, output string) error {
+	listFile := output + ".segments.txt"
+	var list strings.Builder
+	for _, segment := range segments {
+		absolute, err := filepath.Abs(segment)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&list, "file '%s'\n", strings.ReplaceAll(absolute, "'", `'\''`))
+	}
+	if err := os.WriteFile(listFile, []byte(list.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write segment list: %v", err)
+	}
+	defer os.Remove(listFile)
+
+	cmd := exec.Command("ffmpeg", "-y", "-f", "concat", "-safe", "0", "-i", listFile, "-c", "copy", "-movflags", "+faststart", output)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error stitching segments: %v", err)
+	}
+	return nil
+}
+
+// RecordStream records a livestream into rolling segments until it ends, MaxDuration is
+// reached or the user presses Ctrl+C, reconnecting when the connection drops. Segments are
+// complete files as soon as they roll over, so they can be clipped while recording continues.
+// Afterwards they are stitched into one MP4 that SplitVideo and transcription accept as is.
+func RecordStream(streamURL string, opts RecordOptions) (string, error) {
+	if opts.SegmentLength <= 0 {
+		opts.SegmentLength = 10 * time.Minute
+	}
+	if opts.MaxReconnects <= 0 {
+		opts.MaxReconnects = 5
+	}
+
+	provider := ProviderFor(streamURL)
+	metadata, err := provider.FetchMetadata(streamURL)
+	if err != nil {
+		fmt.Printf("⚠️ Could not fetch metadata for %s: %v\n", streamURL, err)
+		metadata = &VideoMetadata{}
+	}
+
+	segmentDir := filepath.Join("./output/recordings", uuid.New().String())
+	if err := os.MkdirAll(segmentDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create recording directory: %v", err)
+	}
+	fmt.Printf("🔴 Recording %s into %s (%s segments)\n", streamURL, segmentDir, opts.SegmentLength)
+
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	go func() {
+		<-interrupts
+		fmt.Println("\n⏹️ Stopping recording...")
+		stopOnce.Do(func() { close(stop) })
+	}()
+	stopped := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+			return false
+		}
+	}
+
+	var recorded time.Duration
+	failures := 0
+	for session := 1; !stopped(); session++ {
+		var limit time.Duration
+		if opts.MaxDuration > 0 {
+			if limit = opts.MaxDuration - recorded; limit <= 0 {
+				break
+			}
+		}
+
+		started := time.Now()
+		inputs, err := liveStreamInputs(streamURL)
+		if err == nil {
+			err = recordSession(inputs, segmentDir, session, opts.SegmentLength, limit, stop)
+		}
+		elapsed := time.Since(started)
+		recorded += elapsed
+
+		if stopped() || (opts.MaxDuration > 0 && recorded >= opts.MaxDuration) {
+			break
+		}
+		if live, liveErr := streamIsLive(streamURL); liveErr == nil && !live {
+			fmt.Println("📴 Stream ended")
+			break
+		}
+
+		// A session that ran for a while was a real recording, so the drop doesn't count against the limit
+		if elapsed > time.Minute {
+			failures = 0
+		}
+		failures++
+		if failures > opts.MaxReconnects {
+			fmt.Printf("❌ Giving up after %d failed reconnects (last error: %v)\n", opts.MaxReconnects, err)
+			break
+		}
+		wait := time.Duration(failures) * 5 * time.Second
+		fmt.Printf("🔌 Connection lost (%v), reconnecting in %s (%d/%d)...\n", err, wait, failures, opts.MaxReconnects)
+		time.Sleep(wait)
+	}
+
+	segments, err := listSegments(segmentDir)
+	if err != nil {
+		return "", err
+	}
+	if len(segments) == 0 {
+		return "", fmt.Errorf("nothing was recorded")
+	}
+
+	template := opts.NameTemplate
+	if template == "" {
+		template = NameTemplateFor(provider.Name())
+	}
+	outputFile, err := ReserveNamedOutputPath(template, metadata, "mp4", "_live")
+	if err != nil {
+		return "", fmt.Errorf("error naming output file: %v", err)
+	}
+	defer ReleaseOutputPath(outputFile)
+
+	fmt.Printf("🧵 Stitching %d segment(s)...\n", len(segments))
+	if err := StitchSegments(segments, outputFile); err != nil {
+		return "", fmt.Errorf("%v (segments kept in %s)", err, segmentDir)
+	}
+
+	if err := WriteSidecar(outputFile, streamURL, metadata, nil); err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+	}
+	if !opts.KeepSegments {
+		if err := os.RemoveAll(segmentDir); err != nil {
+			fmt.Printf("⚠️ Could not remove segments: %v\n", err)
+		}
+	}
+
+	fmt.Printf("✅ Recording saved as %s\n", outputFile)
+	return outputFile, nil
+}
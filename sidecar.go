@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// MediaSidecar records where a downloaded file came from. It is saved next to the
+// media as "<file>.json" so later steps (publish, attribution, dedupe) can read it.
+type MediaSidecar struct {
+	SourceURL          string    `json:"sourceUrl"`
+	Title              string    `json:"title"`
+	Uploader           string    `json:"uploader"`
+	UploadDate         string    `json:"uploadDate,omitempty"` // YYYY-MM-DD
+	Duration           float64   `json:"durationSeconds"`
+	OriginalVideoCodec string    `json:"originalVideoCodec,omitempty"`
+	OriginalAudioCodec string    `json:"originalAudioCodec,omitempty"`
+	OutputVideoCodec   string    `json:"outputVideoCodec,omitempty"`
+	OutputAudioCodec   string    `json:"outputAudioCodec,omitempty"`
+	DownloadedAt       time.Time `json:"downloadedAt"`
+	SHA256             string    `json:"sha256"`
+}
+
+// SidecarPath returns the sidecar file that belongs to a media file
+func SidecarPath(mediaPath string) string {
+	return mediaPath + ".json"
+}
+
+// fileSHA256 hashes a file's contents
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// WriteSidecar saves the sidecar for a finished download. original describes the file as
+// downloaded before any re-encoding and may be nil, in which case the output codecs are used.
+func WriteSidecar(mediaPath, sourceURL string, metadata *VideoMetadata, original *MediaInfo) error {
+	if metadata == nil {
+		metadata = &VideoMetadata{}
+	}
+
+	sidecar := MediaSidecar{
+		SourceURL:    sourceURL,
+		Title:        metadata.Title,
+		Uploader:     metadata.Uploader,
+		Duration:     metadata.Duration,
+		DownloadedAt: time.Now().UTC(),
+	}
+	if uploaded := metadata.UploadTime(); !uploaded.IsZero() {
+		sidecar.UploadDate = uploaded.Format("2006-01-02")
+	}
+
+	output, err := ProbeMedia(mediaPath)
+	if err != nil {
+		fmt.Printf("⚠️ Could not probe %s for the sidecar: %v\n", mediaPath, err)
+	} else {
+		sidecar.OutputVideoCodec = output.VideoCodec
+		sidecar.OutputAudioCodec = output.AudioCodec
+		if output.Duration > 0 {
+			sidecar.Duration = output.Duration
+		}
+		if original == nil {
+			original = output
+		}
+	}
+	if original != nil {
+		sidecar.OriginalVideoCodec = original.VideoCodec
+		sidecar.OriginalAudioCodec = original.AudioCodec
+	}
+
+	sidecar.SHA256, err = fileSHA256(mediaPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %v", mediaPath, err)
+	}
+
+	data, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sidecar: %v", err)
+	}
+	if err := os.WriteFile(SidecarPath(mediaPath), data, 0644); err != nil {
+		return fmt.Errorf("failed to write sidecar: %v", err)
+	}
+	return nil
+}
+
+// ReadSidecar loads the sidecar of a media file
+func ReadSidecar(mediaPath string) (*MediaSidecar, error) {
+	data, err := os.ReadFile(SidecarPath(mediaPath))
+	if err != nil {
+		return nil, err
+	}
+
+	var sidecar MediaSidecar
+	if err := json.Unmarshal(data, &sidecar); err != nil {
+		return nil, fmt.Errorf("failed to parse sidecar of %s: %v", mediaPath, err)
+	}
+	return &sidecar, nil
+}
+
+// mediaLabel returns the media path followed by the URL its sidecar says it came from,
+// so duplicate reports show where each copy was downloaded
+func mediaLabel(mediaPath string) string {
+	if sidecar, err := ReadSidecar(mediaPath); err == nil && sidecar.SourceURL != "" {
+		return fmt.Sprintf("%s (%s)", mediaPath, sidecar.SourceURL)
+	}
+	return mediaPath
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMediaLabel(t *testing.T) {
+	dir := t.TempDir()
+	withSidecar := filepath.Join(dir, "talk.mp4")
+	data, err := json.Marshal(MediaSidecar{SourceURL: "https://example.com/talk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(SidecarPath(withSidecar), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.mp4")
+	if err := os.WriteFile(SidecarPath(broken), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{withSidecar, withSidecar + " (https://example.com/talk)"},
+		{broken, broken},
+		{filepath.Join(dir, "missing.mp4"), filepath.Join(dir, "missing.mp4")},
+	}
+	for _, tt := range tests {
+		if got := mediaLabel(tt.path); got != tt.want {
+			t.Errorf("mediaLabel(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// SyncResult records what sync-branches did to one branch
+type SyncResult struct {
+	Branch string
+	Status string // "up to date", "rebased", "merged", "conflict" or "error"
+	Detail string
+}
+
+// SyncOptions configures SyncBranches
+type SyncOptions struct {
+	Strategy string // "rebase" or "merge"
+	Pattern  string // glob limiting which branches are synced
+	NoFetch  bool
+}
+
+// syncStrategy returns the configured strategy: the flag value, then SYNC_BRANCHES_STRATEGY, then rebase
+func syncStrategy(flagValue string) (string, error) {
+	strategy := flagValue
+	if strategy == "" {
+		strategy = os.Getenv("SYNC_BRANCHES_STRATEGY")
+	}
+	if strategy == "" {
+		strategy = "rebase"
+	}
+	if strategy != "rebase" && strategy != "merge" {
+		return "", fmt.Errorf("unknown sync strategy %q (use rebase or merge)", strategy)
+	}
+	return strategy, nil
+}
+
+// SyncBranches fetches, fast-forwards the default branch and then rebases or merges every
+// other local branch onto it. Branches that conflict are aborted and reported, never left half-done.
+func SyncBranches(opts SyncOptions) ([]SyncResult, error) {
+	strategy, err := syncStrategy(opts.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := runGit("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return nil, fmt.Errorf("error checking working tree: %v", err)
+	}
+	if status != "" {
+		return nil, fmt.Errorf("working tree has uncommitted changes; commit or stash them first")
+	}
+
+	if !opts.NoFetch {
+		fmt.Println("🔄 Fetching from remotes...")
+		if _, err := runGit("fetch", "--all", "--prune"); err != nil {
+			return nil, fmt.Errorf("error fetching: %v", err)
+		}
+	}
+
+	defaultBranch, err := DefaultBranch()
+	if err != nil {
+		return nil, err
+	}
+	originalBranch, err := CurrentBranch()
+	if err != nil {
+		return nil, fmt.Errorf("error reading current branch: %v", err)
+	}
+	if originalBranch == "HEAD" {
+		// Detached HEAD: return to the commit itself
+		if originalBranch, err = runGit("rev-parse", "HEAD"); err != nil {
+			return nil, fmt.Errorf("error reading current commit: %v", err)
+		}
+	}
+	// Always return to where the user started, even after a failure
+	defer runGit("checkout", "--quiet", originalBranch)
+
+	if _, err := runGit("checkout", "--quiet", defaultBranch); err != nil {
+		return nil, fmt.Errorf("error checking out %s: %v", defaultBranch, err)
+	}
+	if upstream, err := runGit("rev-parse", "--abbrev-ref", defaultBranch+"@{upstream}"); err == nil {
+		fmt.Printf("⏩ Fast-forwarding %s to %s...\n", defaultBranch, upstream)
+		if _, err := runGit("merge", "--ff-only", upstream); err != nil {
+			return nil, fmt.Errorf("%s cannot be fast-forwarded to %s; resolve it by hand first: %v", defaultBranch, upstream, err)
+		}
+	} else {
+		fmt.Printf("⚠️ %s has no upstream, syncing onto the local branch as is.\n", defaultBranch)
+	}
+
+	output, err := runGit("for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("error listing branches: %v", err)
+	}
+
+	var results []SyncResult
+	for _, branch := range strings.Split(output, "\n") {
+		if branch == "" || branch == defaultBranch {
+			continue
+		}
+		if opts.Pattern != "" {
+			if ok, _ := filepath.Match(opts.Pattern, branch); !ok {
+				continue
+			}
+		}
+
+		results = append(results, syncBranch(branch, defaultBranch, strategy))
+	}
+
+	return results, nil
+}
+
+// syncBranch rebases or merges a single branch onto base, aborting on conflicts
+func syncBranch(branch, base, strategy string) SyncResult {
+	_, behind, err := aheadBehind(branch, base)
+	if err != nil {
+		return SyncResult{Branch: branch, Status: "error", Detail: err.Error()}
+	}
+	if behind == 0 {
+		return SyncResult{Branch: branch, Status: "up to date"}
+	}
+
+	verb := "Rebasing"
+	if strategy == "merge" {
+		verb = "Merging"
+	}
+	fmt.Printf("🔀 %s %s onto %s (%d commits behind)...\n", verb, branch, base, behind)
+	if _, err := runGit("checkout", "--quiet", branch); err != nil {
+		return SyncResult{Branch: branch, Status: "error", Detail: fmt.Sprintf("checkout failed: %v", err)}
+	}
+
+	if strategy == "merge" {
+		_, err = runGit("merge", "--no-edit", base)
+	} else {
+		_, err = runGit("rebase", base)
+	}
+	if err == nil {
+		status := "rebased"
+		if strategy == "merge" {
+			status = "merged"
+		}
+		return SyncResult{Branch: branch, Status: status, Detail: fmt.Sprintf("%d commits", behind)}
+	}
+
+	// Only unmerged paths make it a conflict; anything else (hooks, locks, untracked files
+	// in the way) is an error the user has to look at
+	conflicts, _ := runGit("diff", "--name-only", "--diff-filter=U")
+	if abortErr := abortSync(strategy); abortErr != nil {
+		return SyncResult{Branch: branch, Status: "error", Detail: fmt.Sprintf("%s failed and could not be aborted, resolve it by hand: %v", strategy, abortErr)}
+	}
+	if conflicts == "" {
+		return SyncResult{Branch: branch, Status: "error", Detail: fmt.Sprintf("%s failed: %v", strategy, err)}
+	}
+	files := strings.Split(conflicts, "\n")
+	return SyncResult{Branch: branch, Status: "conflict", Detail: fmt.Sprintf("%s aborted, conflicts in %s", strategy, strings.Join(files, ", "))}
+}
+
+// abortSync aborts the merge or rebase left in progress by a failed sync, if there is one
+func abortSync(strategy string) error {
+	inProgress := false
+	if strategy == "merge" {
+		_, err := runGit("rev-parse", "-q", "--verify", "MERGE_HEAD")
+		inProgress = err == nil
+	} else {
+		for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+			if path, err := runGit("rev-parse", "--git-path", dir); err == nil {
+				if _, err := os.Stat(path); err == nil {
+					inProgress = true
+				}
+			}
+		}
+	}
+	if !inProgress {
+		return nil
+	}
+	_, err := runGit(strategy, "--abort")
+	return err
+}
+
+// PrintSyncSummary prints one line per branch with the outcome of the sync
+func PrintSyncSummary(results []SyncResult) {
+	fmt.Println("\n📋 Sync summary:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	conflicts := 0
+	for _, r := range results {
+		icon := "✅"
+		if r.Status == "conflict" || r.Status == "error" {
+			icon = "❌"
+			conflicts++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", icon, r.Branch, r.Status, r.Detail)
+	}
+	w.Flush()
+
+	if conflicts > 0 {
+		fmt.Printf("\n⚠️ %d branch(es) need manual attention.\n", conflicts)
+	}
+}
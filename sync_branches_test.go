@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestSyncStrategy(t *testing.T) {
+	tests := []struct {
+		flag, env string
+		want      string
+		wantErr   bool
+	}{
+		{"", "", "rebase", false},
+		{"", "merge", "merge", false},
+		{"rebase", "merge", "rebase", false},
+		{"merge", "", "merge", false},
+		{"squash", "", "", true},
+		{"", "cherry-pick", "", true},
+	}
+	for _, tt := range tests {
+		t.Setenv("SYNC_BRANCHES_STRATEGY", tt.env)
+		got, err := syncStrategy(tt.flag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("syncStrategy(%q) with env %q error = %v, wantErr %v", tt.flag, tt.env, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("syncStrategy(%q) with env %q = %q, want %q", tt.flag, tt.env, got, tt.want)
+		}
+	}
+}
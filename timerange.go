@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TimeRange is a section of a video in seconds. End is 0 when the range runs to the end.
+type TimeRange struct {
+	Start float64
+	End   float64
+}
+
+// ParseTimestamp accepts seconds ("90", "90.5"), "MM:SS" or "HH:MM:SS(.ms)". Minutes and
+// seconds below a higher unit must be under 60.
+func ParseTimestamp(value string) (float64, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	var seconds float64
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 || math.IsNaN(n) || math.IsInf(n, 0) || (i > 0 && n >= 60) {
+			return 0, fmt.Errorf("invalid timestamp %q", value)
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds, nil
+}
+
+// ParseTimeRange builds a range from -start/-end or from a -sections value like "10:00-15:00".
+// It returns nil when no range was requested.
+func ParseTimeRange(start, end, sections string) (*TimeRange, error) {
+	if sections != "" {
+		if start != "" || end != "" {
+			return nil, fmt.Errorf("use either -sections or -start/-end, not both")
+		}
+		var found bool
+		start, end, found = strings.Cut(strings.TrimPrefix(sections, "*"), "-")
+		if !found {
+			return nil, fmt.Errorf("invalid section %q, expected START-END", sections)
+		}
+	}
+	if start == "" && end == "" {
+		return nil, nil
+	}
+
+	r := &TimeRange{}
+	var err error
+	if start != "" {
+		if r.Start, err = ParseTimestamp(start); err != nil {
+			return nil, err
+		}
+	}
+	if end != "" && end != "inf" {
+		if r.End, err = ParseTimestamp(end); err != nil {
+			return nil, err
+		}
+		if r.End <= r.Start {
+			return nil, fmt.Errorf("end %s must be after start %s", end, start)
+		}
+	}
+	return r, nil
+}
+
+// formatTimestamp renders seconds as HH:MM:SS(.ms) with the given separator
+func formatTimestamp(seconds float64, sep string) string {
+	// Round to the millisecond first so 59.9996 carries into the seconds instead of ".1000"
+	total := int64(seconds*1000 + 0.5)
+	whole := total / 1000
+	s := fmt.Sprintf("%02d%s%02d%s%02d", whole/3600, sep, whole/60%60, sep, whole%60)
+	if ms := total % 1000; ms > 0 {
+		s += fmt.Sprintf(".%03d", ms)
+	}
+	return s
+}
+
+// YTDLPSection returns the --download-sections value for the range
+func (r *TimeRange) YTDLPSection() string {
+	end := "inf"
+	if r.End > 0 {
+		end = formatTimestamp(r.End, ":")
+	}
+	return fmt.Sprintf("*%s-%s", formatTimestamp(r.Start, ":"), end)
+}
+
+// FFmpegArgs returns the -ss/-to options that select the range
+func (r *TimeRange) FFmpegArgs() []string {
+	args := []string{"-ss", strconv.FormatFloat(r.Start, 'f', 3, 64)}
+	if r.End > 0 {
+		args = append(args, "-to", strconv.FormatFloat(r.End, 'f', 3, 64))
+	}
+	return args
+}
+
+// NameSuffix is appended to output names so ranged downloads say which part they hold
+func (r *TimeRange) NameSuffix() string {
+	end := "end"
+	if r.End > 0 {
+		end = formatTimestamp(r.End, "-")
+	}
+	return fmt.Sprintf("_%s_to_%s", formatTimestamp(r.Start, "-"), end)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"90", 90, false},
+		{"90.5", 90.5, false},
+		{" 1:30 ", 90, false},
+		{"01:02:03", 3723, false},
+		{"00:00:01.250", 1.25, false},
+		{"1:2:3:4", 0, true},
+		{"", 0, true},
+		{"1:xx", 0, true},
+		{"-5", 0, true},
+		{"NaN", 0, true},
+		{"inf", 0, true},
+		{"1:Inf", 0, true},
+		{"1:75", 0, true},
+		{"1:60:00", 0, true},
+		{"75:00", 4500, false},
+		{"1:59.5", 119.5, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimestamp(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTimestamp(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTimestamp(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	tests := []struct {
+		start, end, sections string
+		want                 *TimeRange
+		wantErr              bool
+	}{
+		{"", "", "", nil, false},
+		{"1:00", "", "", &TimeRange{Start: 60}, false},
+		{"", "30", "", &TimeRange{End: 30}, false},
+		{"10", "20", "", &TimeRange{Start: 10, End: 20}, false},
+		{"", "", "10:00-15:00", &TimeRange{Start: 600, End: 900}, false},
+		{"", "", "*1:00-inf", &TimeRange{Start: 60}, false},
+		{"20", "10", "", nil, true},
+		{"10", "10", "", nil, true},
+		{"", "", "10:00", nil, true},
+		{"1", "", "1-2", nil, true},
+		{"abc", "", "", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeRange(tt.start, tt.end, tt.sections)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTimeRange(%q, %q, %q) error = %v, wantErr %v", tt.start, tt.end, tt.sections, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTimeRange(%q, %q, %q) = %+v, want %+v", tt.start, tt.end, tt.sections, got, tt.want)
+		}
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "00:00:00"},
+		{90, "00:01:30"},
+		{3723.25, "01:02:03.250"},
+		{59.9996, "00:01:00"},
+		{3599.9999, "01:00:00"},
+		{1.0004, "00:00:01"},
+		{1.0006, "00:00:01.001"},
+	}
+	for _, tt := range tests {
+		if got := formatTimestamp(tt.seconds, ":"); got != tt.want {
+			t.Errorf("formatTimestamp(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestTimeRangeFormats(t *testing.T) {
+	tests := []struct {
+		r          TimeRange
+		section    string
+		suffix     string
+		ffmpegArgs []string
+	}{
+		{TimeRange{Start: 600, End: 900}, "*00:10:00-00:15:00", "_00-10-00_to_00-15-00", []string{"-ss", "600.000", "-to", "900.000"}},
+		{TimeRange{Start: 61.5}, "*00:01:01.500-inf", "_00-01-01.500_to_end", []string{"-ss", "61.500"}},
+	}
+	for _, tt := range tests {
+		if got := tt.r.YTDLPSection(); got != tt.section {
+			t.Errorf("%+v YTDLPSection() = %q, want %q", tt.r, got, tt.section)
+		}
+		if got := tt.r.NameSuffix(); got != tt.suffix {
+			t.Errorf("%+v NameSuffix() = %q, want %q", tt.r, got, tt.suffix)
+		}
+		if got := tt.r.FFmpegArgs(); !reflect.DeepEqual(got, tt.ffmpegArgs) {
+			t.Errorf("%+v FFmpegArgs() = %q, want %q", tt.r, got, tt.ffmpegArgs)
+		}
+	}
+}
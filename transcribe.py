@@ -1,4 +1,6 @@
 # transcribe.py
+import argparse
+import json
 import sys
 import whisper
 import os
@@ -11,11 +13,32 @@ warnings.filterwarnings("ignore", category=UserWarning)
 sys.stdout.reconfigure(encoding='utf-8')
 
 
-def transcribe(audio_file):
-    """Transcribes an audio file using Whisper and returns the text."""
-    model = whisper.load_model("base")
-    result = model.transcribe(audio_file)
-    return result["text"]
+def transcribe(audio_file, model_name="base", language=None, words=False):
+    """Transcribes an audio file using Whisper and returns the full result."""
+    model = whisper.load_model(model_name)
+    return model.transcribe(audio_file, language=language, word_timestamps=words)
+
+
+def save_json(result, file_path):
+    """Saves the text, language and timed segments as JSON for the Go tools."""
+    data = {
+        "text": result["text"].strip(),
+        "language": result.get("language", ""),
+        "segments": [
+            {
+                "start": s["start"],
+                "end": s["end"],
+                "text": s["text"].strip(),
+                "words": [
+                    {"word": w["word"].strip(), "start": w["start"], "end": w["end"]}
+                    for w in s.get("words", [])
+                ],
+            }
+            for s in result.get("segments", [])
+        ],
+    }
+    with open(file_path, "w", encoding="utf-8") as file:
+        json.dump(data, file, ensure_ascii=False)
 
 
 def save_transcription(text, file_path):
@@ -26,11 +49,19 @@ def save_transcription(text, file_path):
 
 
 if __name__ == "__main__":
-    if len(sys.argv) < 2:
-        print("Please provide the audio file path")
-        sys.exit(1)
+    parser = argparse.ArgumentParser(description="Transcribe an audio or video file with Whisper")
+    parser.add_argument("audio_file", help="Audio or video file to transcribe")
+    parser.add_argument("--model", default="base", help="Whisper model name")
+    parser.add_argument("--language", default=None, help="Spoken language; detected when omitted")
+    parser.add_argument("--words", action="store_true", help="Also time every word")
+    parser.add_argument("--json", dest="json_file", help="Write the structured result to this file instead")
+    args = parser.parse_args()
+
+    audio_file = args.audio_file
 
-    audio_file = sys.argv[1]
+    if args.json_file:
+        save_json(transcribe(audio_file, args.model, args.language, args.words), args.json_file)
+        sys.exit(0)
 
     # Ensure output directory exists
     output_dir = "./output/transcriptions"
@@ -47,7 +78,7 @@ if __name__ == "__main__":
     else:
         # Transcribe and save if file doesn't exist
         print("🎤 Transcribing audio...")
-        transcription = transcribe(audio_file)
+        transcription = transcribe(audio_file, args.model, args.language)["text"]
         save_transcription(transcription, transcription_file)
     
     # Print the transcription file path for downstream processes
@@ -1,21 +1,459 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
-// TranscribeAudio transcribes audio from a .wav file to text using an external Python script
-func TranscribeAudio(audioFile string) {
-	fmt.Println("🔍 Transcribing video audio to text...")
+// TranscriptWord is one word with its timing
+type TranscriptWord struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"` // seconds
+	End   float64 `json:"end"`
+}
+
+// TranscriptSegment is one timed stretch of speech
+type TranscriptSegment struct {
+	Start float64          `json:"start"` // seconds
+	End   float64          `json:"end"`
+	Text  string           `json:"text"`
+	Words []TranscriptWord `json:"words,omitempty"` // only with word timestamps
+}
+
+// Transcript is the structured result of a transcription
+type Transcript struct {
+	Text     string              `json:"text"`
+	Language string              `json:"language,omitempty"`
+	Segments []TranscriptSegment `json:"segments,omitempty"`
+	Backend  string              `json:"backend,omitempty"`
+	Model    string              `json:"model,omitempty"`
+}
+
+// Transcriber turns the speech in an audio or video file into text
+type Transcriber interface {
+	Name() string
+	Model() string
+	Transcribe(mediaFile string) (*Transcript, error)
+}
+
+// TranscriptionOptions selects the backend, model and spoken language. Empty fields fall back
+// to TRANSCRIBE_BACKEND, TRANSCRIBE_MODEL and TRANSCRIBE_LANGUAGE, then to each backend's default.
+type TranscriptionOptions struct {
+	Backend  string
+	Model    string
+	Language string // ISO 639-1 code; empty lets the backend detect it
+	Words    bool   // also time every word
+
+	ChunkLength time.Duration // split longer recordings at silences into chunks of about this length; 0 disables
+	Workers     int           // chunks transcribed at once; 0 picks the backend's default
+	Retries     int           // attempts after a failed chunk
+}
+
+// AddTranscriptionFlags registers -backend, -model and -language on a command
+func AddTranscriptionFlags(fs *flag.FlagSet) *TranscriptionOptions {
+	opts := &TranscriptionOptions{}
+	fs.StringVar(&opts.Backend, "backend", "", "Transcription backend: whisper, whisper-cpp or openai (default TRANSCRIBE_BACKEND or whisper)")
+	fs.StringVar(&opts.Model, "model", "", "Transcription model (default TRANSCRIBE_MODEL or the backend's default)")
+	fs.StringVar(&opts.Language, "language", "", "Spoken language code, e.g. en (default TRANSCRIBE_LANGUAGE or detected)")
+	fs.BoolVar(&opts.Words, "words", false, "Also save word-level timestamps")
+	fs.DurationVar(&opts.ChunkLength, "chunk", 10*time.Minute, "Transcribe longer recordings in chunks of about this length split at silences, when more than one worker runs (0 disables)")
+	fs.IntVar(&opts.Workers, "workers", 0, "Chunks transcribed in parallel (default 4 for openai, 1 for local backends)")
+	fs.IntVar(&opts.Retries, "retries", 2, "Retries for a failed chunk")
+	return opts
+}
+
+// transcriberBackends maps backend names to their constructors
+var transcriberBackends = map[string]func(opts TranscriptionOptions) Transcriber{
+	"whisper": func(opts TranscriptionOptions) Transcriber {
+		return &whisperPythonTranscriber{model: orDefault(opts.Model, "base"), language: opts.Language, words: opts.Words}
+	},
+	"whisper-cpp": func(opts TranscriptionOptions) Transcriber {
+		return &whisperCppTranscriber{model: orDefault(opts.Model, "base"), language: opts.Language, words: opts.Words}
+	},
+	"openai": func(opts TranscriptionOptions) Transcriber {
+		return &openAITranscriber{model: orDefault(opts.Model, "whisper-1"), language: opts.Language, words: opts.Words}
+	},
+}
+
+const defaultTranscriberBackend = "whisper"
+
+// orDefault returns value, or fallback when value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// withEnvDefaults fills empty options from the environment
+func (o TranscriptionOptions) withEnvDefaults() TranscriptionOptions {
+	o.Backend = orDefault(o.Backend, orDefault(os.Getenv("TRANSCRIBE_BACKEND"), defaultTranscriberBackend))
+	o.Model = orDefault(o.Model, os.Getenv("TRANSCRIBE_MODEL"))
+	o.Language = orDefault(o.Language, os.Getenv("TRANSCRIBE_LANGUAGE"))
+	return o
+}
+
+// NewTranscriber returns the transcriber selected by opts
+func NewTranscriber(opts TranscriptionOptions) (Transcriber, error) {
+	opts = opts.withEnvDefaults()
+	constructor, ok := transcriberBackends[opts.Backend]
+	if !ok {
+		var names []string
+		for name := range transcriberBackends {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown transcription backend %q (available: %s)", opts.Backend, strings.Join(names, ", "))
+	}
+	return constructor(opts), nil
+}
+
+// convertForTranscription writes the audio of mediaFile as 16 kHz mono, which is what Whisper
+// models work at, in the container the output extension selects
+func convertForTranscription(mediaFile, output string, codecArgs ...string) error {
+	args := append([]string{"-y", "-i", mediaFile, "-vn", "-ar", "16000", "-ac", "1"}, codecArgs...)
+	cmd := exec.Command("ffmpeg", append(args, output)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to prepare audio for transcription: %v\n%s", err, out)
+	}
+	return nil
+}
+
+// whisperPythonTranscriber runs the openai-whisper package through transcribe.py
+type whisperPythonTranscriber struct {
+	model    string
+	language string
+	words    bool
+}
+
+func (w *whisperPythonTranscriber) Name() string  { return "whisper" }
+func (w *whisperPythonTranscriber) Model() string { return w.model }
+
+func (w *whisperPythonTranscriber) Transcribe(mediaFile string) (*Transcript, error) {
+	resultFile, err := os.CreateTemp("", "transcript_*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transcript file: %v", err)
+	}
+	resultFile.Close()
+	defer os.Remove(resultFile.Name())
+
+	args := []string{"transcribe.py", mediaFile, "--model", w.model, "--json", resultFile.Name()}
+	if w.language != "" {
+		args = append(args, "--language", w.language)
+	}
+	if w.words {
+		args = append(args, "--words")
+	}
+	if output, err := exec.Command("python", args...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("whisper failed: %v\n%s", err, output)
+	}
+
+	data, err := os.ReadFile(resultFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read whisper output: %v", err)
+	}
+	var transcript Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return nil, fmt.Errorf("failed to parse whisper output: %v", err)
+	}
+	return &transcript, nil
+}
+
+// whisperCppTranscriber runs a whisper.cpp CLI binary, WHISPER_CPP_BIN or whisper-cli on
+// the PATH. The model is a path to a ggml model file, or a model name looked up as
+// ggml-<name>.bin in WHISPER_CPP_MODELS_DIR (default ./models).
+type whisperCppTranscriber struct {
+	model    string
+	language string
+	words    bool
+}
+
+func (w *whisperCppTranscriber) Name() string  { return "whisper-cpp" }
+func (w *whisperCppTranscriber) Model() string { return w.model }
+
+// modelPath resolves the configured model to a ggml file
+func (w *whisperCppTranscriber) modelPath() (string, error) {
+	path := w.model
+	if !strings.ContainsRune(path, os.PathSeparator) && !strings.HasSuffix(path, ".bin") {
+		path = filepath.Join(orDefault(os.Getenv("WHISPER_CPP_MODELS_DIR"), "./models"), "ggml-"+w.model+".bin")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("whisper.cpp model not found: %v", err)
+	}
+	return path, nil
+}
+
+// whisperCppOutput is the subset of whisper.cpp's --output-json file we use
+type whisperCppOutput struct {
+	Result struct {
+		Language string `json:"language"`
+	} `json:"result"`
+	Transcription []struct {
+		Offsets struct {
+			From int64 `json:"from"` // milliseconds
+			To   int64 `json:"to"`
+		} `json:"offsets"`
+		Text   string `json:"text"`
+		Tokens []struct {
+			Text    string `json:"text"`
+			Offsets struct {
+				From int64 `json:"from"`
+				To   int64 `json:"to"`
+			} `json:"offsets"`
+		} `json:"tokens"` // only with --output-json-full
+	} `json:"transcription"`
+}
+
+func (w *whisperCppTranscriber) Transcribe(mediaFile string) (*Transcript, error) {
+	model, err := w.modelPath()
+	if err != nil {
+		return nil, err
+	}
+
+	workDir, err := os.MkdirTemp("", "whisper_cpp_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	// whisper.cpp only reads 16 kHz WAV
+	wavFile := filepath.Join(workDir, "audio.wav")
+	if err := convertForTranscription(mediaFile, wavFile, "-c:a", "pcm_s16le"); err != nil {
+		return nil, err
+	}
+
+	outputBase := filepath.Join(workDir, "transcript")
+	args := []string{"-m", model, "-f", wavFile, "--output-json", "--output-file", outputBase, "--language", orDefault(w.language, "auto")}
+	if w.words {
+		args = append(args, "--output-json-full")
+	}
+	binary := orDefault(os.Getenv("WHISPER_CPP_BIN"), "whisper-cli")
+	if output, err := exec.Command(binary, args...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("whisper.cpp failed: %v\n%s", err, output)
+	}
+
+	data, err := os.ReadFile(outputBase + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read whisper.cpp output: %v", err)
+	}
+	transcript, err := parseWhisperCppJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse whisper.cpp output: %v", err)
+	}
+	return transcript, nil
+}
+
+// parseWhisperCppJSON converts a whisper.cpp JSON file, building words from the tokens of
+// --output-json-full output
+func parseWhisperCppJSON(data []byte) (*Transcript, error) {
+	var result whisperCppOutput
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	transcript := &Transcript{Language: result.Result.Language}
+	var texts []string
+	for _, segment := range result.Transcription {
+		text := strings.TrimSpace(segment.Text)
+		if text == "" {
+			continue
+		}
+		transcriptSegment := TranscriptSegment{
+			Start: float64(segment.Offsets.From) / 1000,
+			End:   float64(segment.Offsets.To) / 1000,
+			Text:  text,
+		}
+
+		// Tokens are word pieces; a leading space starts a new word
+		for _, token := range segment.Tokens {
+			if strings.HasPrefix(token.Text, "[_") || strings.TrimSpace(token.Text) == "" {
+				continue // [_BEG_], [_TT_n] and other special tokens
+			}
+			start, end := float64(token.Offsets.From)/1000, float64(token.Offsets.To)/1000
+			words := transcriptSegment.Words
+			if len(words) > 0 && !strings.HasPrefix(token.Text, " ") {
+				words[len(words)-1].Word += token.Text
+				words[len(words)-1].End = end
+				continue
+			}
+			transcriptSegment.Words = append(words, TranscriptWord{Word: strings.TrimSpace(token.Text), Start: start, End: end})
+		}
+
+		transcript.Segments = append(transcript.Segments, transcriptSegment)
+		texts = append(texts, text)
+	}
+	transcript.Text = strings.Join(texts, " ")
+	return transcript, nil
+}
+
+// openAITranscriber posts audio to an OpenAI-compatible /audio/transcriptions endpoint.
+// OPENAI_BASE_URL points it at a self-hosted server; OPENAI_API_KEY is sent when set.
+type openAITranscriber struct {
+	model    string
+	language string
+	words    bool
+}
+
+func (o *openAITranscriber) Name() string  { return "openai" }
+func (o *openAITranscriber) Model() string { return o.model }
+
+// openAITranscriptionResponse is the verbose_json response of /audio/transcriptions
+type openAITranscriptionResponse struct {
+	Text     string              `json:"text"`
+	Language string              `json:"language"`
+	Segments []TranscriptSegment `json:"segments"`
+	Words    []TranscriptWord    `json:"words"` // with timestamp_granularities[]=word
+}
+
+func (o *openAITranscriber) Transcribe(mediaFile string) (*Transcript, error) {
+	baseURL := strings.TrimSuffix(orDefault(os.Getenv("OPENAI_BASE_URL"), "https://api.openai.com/v1"), "/")
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" && strings.HasPrefix(baseURL, "https://api.openai.com") {
+		return nil, fmt.Errorf("missing OPENAI_API_KEY environment variable")
+	}
+
+	// Uploads are limited to 25 MB; 16 kHz mono MP3 fits about three hours
+	audioFile, err := os.CreateTemp("", "transcribe_*.mp3")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audio file: %v", err)
+	}
+	audioFile.Close()
+	defer os.Remove(audioFile.Name())
+	if err := convertForTranscription(mediaFile, audioFile.Name(), "-c:a", "libmp3lame", "-b:a", "16k"); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	fields := [][2]string{{"model", o.model}, {"response_format", "verbose_json"}}
+	if o.language != "" {
+		fields = append(fields, [2]string{"language", o.language})
+	}
+	if o.words {
+		fields = append(fields, [2]string{"timestamp_granularities[]", "segment"}, [2]string{"timestamp_granularities[]", "word"})
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return nil, err
+		}
+	}
+	part, err := form.CreateFormFile("file", filepath.Base(audioFile.Name()))
+	if err != nil {
+		return nil, err
+	}
+	audio, err := os.Open(audioFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audio file: %v", err)
+	}
+	_, err = io.Copy(part, audio)
+	audio.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio file: %v", err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transcription request: %v", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send transcription request: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcription response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transcription API error: %d %s\nResponse: %s", resp.StatusCode, http.StatusText(resp.StatusCode), string(respBody))
+	}
+
+	var result openAITranscriptionResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse transcription response: %v", err)
+	}
+	for i := range result.Segments {
+		result.Segments[i].Text = strings.TrimSpace(result.Segments[i].Text)
+	}
+	assignWords(result.Segments, result.Words)
+	return &Transcript{Text: strings.TrimSpace(result.Text), Language: result.Language, Segments: result.Segments}, nil
+}
+
+// assignWords files each word under the segment it starts in
+func assignWords(segments []TranscriptSegment, words []TranscriptWord) {
+	i := 0
+	for _, word := range words {
+		for i < len(segments)-1 && word.Start >= segments[i+1].Start {
+			i++
+		}
+		if i < len(segments) {
+			word.Word = strings.TrimSpace(word.Word)
+			segments[i].Words = append(segments[i].Words, word)
+		}
+	}
+}
 
-	// Call the transcribe.py Python script to transcribe the audio file
-	cmd := exec.Command("python", "transcribe.py", audioFile)
-	output, err := cmd.CombinedOutput()
+// TranscribeAudio transcribes an audio or video file with the selected backend and saves it
+// to output/transcriptions/<name> as text, SRT, WebVTT and JSON. Transcripts are cached by
+// the audio's content, backend, model and language, so files that merely share a name never
+// share a transcript. Captions or an imported transcript of the same audio are used instead
+// of transcribing unless a backend, model or word timestamps were asked for.
+func TranscribeAudio(mediaFile string, opts TranscriptionOptions) (*Transcript, error) {
+	if _, err := os.Stat(mediaFile); err != nil {
+		return nil, fmt.Errorf("cannot transcribe %s: %v", mediaFile, err)
+	}
+	transcriber, err := NewTranscriber(opts)
 	if err != nil {
-		fmt.Printf("❌ Failed to transcribe audio: %v\n%s", err, output)
-		return
+		return nil, err
 	}
+	substitutes := opts.acceptsSubstitutes()
+	opts = opts.withEnvDefaults()
+	name := captionNameFor(mediaFile)
 
-	fmt.Println("✅ Transcription complete!")
+	transcript, entry, err := cachedTranscript(mediaFile, transcriber, opts, substitutes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up transcription cache: %v", err)
+	}
+	if transcript != nil {
+		fmt.Printf("📂 Using cached transcription %s (%s)\n", entry.Key, entry.Backend)
+	} else {
+		fmt.Printf("🔍 Transcribing %s with %s...\n", mediaFile, transcriber.Name())
+		if transcript, err = transcribeChunked(transcriber, mediaFile, entry.Key, opts); err != nil {
+			return nil, fmt.Errorf("failed to transcribe audio: %v", err)
+		}
+		if strings.TrimSpace(transcript.Text) == "" {
+			return nil, fmt.Errorf("transcription of %s is empty", mediaFile)
+		}
+		transcript.Backend = transcriber.Name()
+		transcript.Model = transcriber.Model()
+		if err := storeTranscript(mediaFile, entry, transcript); err != nil {
+			fmt.Printf("⚠️ Could not cache transcription: %v\n", err)
+		}
+	}
+
+	files, err := SaveTranscript(transcript, name)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("✅ Transcription saved to %s\n", strings.Join(files, ", "))
+	return transcript, nil
 }
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backends of transcripts that weren't made by a Transcriber. They are reused for any
+// backend or model since they're the only transcript of their kind.
+const (
+	transcriptSourceCaptions = "captions"
+	transcriptSourceImport   = "import"
+)
+
+// TranscriptionCacheEntry is one cached transcript. The key covers the audio content and the
+// backend, model, language and word timing it was made with.
+type TranscriptionCacheEntry struct {
+	Key       string    `json:"key"`
+	AudioHash string    `json:"audioHash"`
+	Backend   string    `json:"backend"`
+	Model     string    `json:"model,omitempty"`
+	Language  string    `json:"language,omitempty"`
+	Words     bool      `json:"words,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Files     []string  `json:"files"` // media files whose audio this is
+}
+
+// TranscribedFile remembers the audio hash of a media file and the name its transcript is
+// saved under in output/transcriptions, so the hash is only recomputed when the file changes
+type TranscribedFile struct {
+	AudioHash string    `json:"audioHash"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"modTime"`
+	Name      string    `json:"name"`
+}
+
+// TranscriptionCache stores transcripts as <key>.json with an index.json mapping media files
+// and keys to entries
+type TranscriptionCache struct {
+	dir     string
+	Entries map[string]*TranscriptionCacheEntry `json:"entries"`
+	Files   map[string]*TranscribedFile         `json:"files"`
+}
+
+// transcriptionCacheMu serializes index updates from concurrent transcriptions
+var transcriptionCacheMu sync.Mutex
+
+// transcriptionCacheDir returns TRANSCRIPTION_CACHE_DIR or ./output/transcriptions/cache
+func transcriptionCacheDir() string {
+	if dir := os.Getenv("TRANSCRIPTION_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(transcriptionDir, "cache")
+}
+
+// LoadTranscriptionCache reads the cache index, treating a missing file as empty
+func LoadTranscriptionCache(dir string) (*TranscriptionCache, error) {
+	cache := &TranscriptionCache{dir: dir, Entries: map[string]*TranscriptionCacheEntry{}, Files: map[string]*TranscribedFile{}}
+	data, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, fmt.Errorf("failed to read transcription cache index: %v", err)
+	}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("failed to parse transcription cache index: %v", err)
+	}
+	if cache.Entries == nil {
+		cache.Entries = map[string]*TranscriptionCacheEntry{}
+	}
+	if cache.Files == nil {
+		cache.Files = map[string]*TranscribedFile{}
+	}
+	return cache, nil
+}
+
+// Save writes the index back to disk
+func (c *TranscriptionCache) Save() error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create transcription cache directory: %v", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, "index.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write transcription cache index: %v", err)
+	}
+	return nil
+}
+
+// ComputeAudioHash hashes the decoded audio of a media file, so the same recording gets the
+// same hash whatever its file name or container
+func ComputeAudioHash(path string) (string, error) {
+	cmd := exec.Command("ffmpeg", "-v", "error", "-i", path, "-map", "0:a:0", "-f", "hash", "-hash", "sha256", "-")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to hash audio of %s: %v", path, err)
+	}
+	hash := strings.TrimPrefix(strings.TrimSpace(string(output)), "SHA256=")
+	if hash == "" {
+		return "", fmt.Errorf("%s has no audio", path)
+	}
+	return hash, nil
+}
+
+// trackFile returns the index record of a media file, hashing its audio again only if the
+// file changed since it was last seen
+func (c *TranscriptionCache) trackFile(path string) (string, *TranscribedFile, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return "", nil, err
+	}
+	stat, err := os.Stat(absolute)
+	if err != nil {
+		return "", nil, err
+	}
+	if file, ok := c.Files[absolute]; ok && file.Size == stat.Size() && file.ModTime.Equal(stat.ModTime()) {
+		return absolute, file, nil
+	}
+
+	hash, err := ComputeAudioHash(absolute)
+	if err != nil {
+		return "", nil, err
+	}
+	file := &TranscribedFile{AudioHash: hash, Size: stat.Size(), ModTime: stat.ModTime(), Name: captionNameFor(path)}
+	c.Files[absolute] = file
+	return absolute, file, nil
+}
+
+// transcriptionCacheKey combines the audio hash with everything that changes the transcript
+func transcriptionCacheKey(audioHash, backend, model, language string, words bool) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s|%t", audioHash, backend, model, language, words)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Get returns the cached transcript of an entry
+func (c *TranscriptionCache) Get(entry *TranscriptionCacheEntry) (*Transcript, error) {
+	return ReadTranscriptFile(filepath.Join(c.dir, entry.Key+".json"))
+}
+
+// Put stores a transcript under entry's key and records that file uses it
+func (c *TranscriptionCache) Put(entry *TranscriptionCacheEntry, transcript *Transcript, file string) error {
+	content, err := RenderTranscript(transcript, "json", false)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create transcription cache directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, entry.Key+".json"), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write cached transcript: %v", err)
+	}
+	c.Entries[entry.Key] = entry
+	c.addFile(entry, file)
+	return nil
+}
+
+// addFile lists file under an entry once
+func (c *TranscriptionCache) addFile(entry *TranscriptionCacheEntry, file string) {
+	for _, existing := range entry.Files {
+		if existing == file {
+			return
+		}
+	}
+	entry.Files = append(entry.Files, file)
+	sort.Strings(entry.Files)
+}
+
+// find returns the entry for key. With substitutes it prefers the newest captions or imported
+// transcript of the audio, which take the place of transcribing it.
+func (c *TranscriptionCache) find(key, audioHash string, substitutes bool) *TranscriptionCacheEntry {
+	entries := c.sortedEntries()
+	for i := len(entries) - 1; i >= 0 && substitutes; i-- {
+		entry := entries[i]
+		if entry.AudioHash == audioHash && (entry.Backend == transcriptSourceCaptions || entry.Backend == transcriptSourceImport) {
+			return entry
+		}
+	}
+	return c.Entries[key]
+}
+
+// nameClaimedElsewhere reports whether a transcript saved as name belongs to a file with
+// different audio, like the clip_1 of another video
+func (c *TranscriptionCache) nameClaimedElsewhere(name, audioHash string) bool {
+	for _, file := range c.Files {
+		if file.Name == name && file.AudioHash != audioHash {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedEntries returns the entries oldest first
+func (c *TranscriptionCache) sortedEntries() []*TranscriptionCacheEntry {
+	var entries []*TranscriptionCacheEntry
+	for _, entry := range c.Entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// minKeyPrefix is the shortest key prefix Lookup accepts, so a short query can't match
+// (and invalidate) unrelated entries by accident
+const minKeyPrefix = 8
+
+// Lookup returns the entries matching a media file path, a key, or a key prefix of at
+// least minKeyPrefix characters
+func (c *TranscriptionCache) Lookup(query string) []*TranscriptionCacheEntry {
+	var audioHash string
+	if absolute, err := filepath.Abs(query); err == nil {
+		if file, ok := c.Files[absolute]; ok {
+			audioHash = file.AudioHash
+		}
+	}
+
+	var matches []*TranscriptionCacheEntry
+	for _, entry := range c.sortedEntries() {
+		byKey := entry.Key == query || (len(query) >= minKeyPrefix && strings.HasPrefix(entry.Key, query))
+		if (audioHash != "" && entry.AudioHash == audioHash) || byKey {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// Invalidate removes entries along with the transcripts saved from them in
+// output/transcriptions, so the next run transcribes those files again. A file another entry
+// still lists is kept, and so are saved transcripts whose name a remaining file still uses.
+func (c *TranscriptionCache) Invalidate(entries []*TranscriptionCacheEntry) error {
+	var paths []string
+	for _, entry := range entries {
+		if err := os.Remove(filepath.Join(c.dir, entry.Key+".json")); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove cached transcript: %v", err)
+		}
+		delete(c.Entries, entry.Key)
+		paths = append(paths, entry.Files...)
+	}
+
+	listed := map[string]bool{}
+	for _, entry := range c.Entries {
+		for _, path := range entry.Files {
+			listed[path] = true
+		}
+	}
+	names := map[string]bool{}
+	for _, path := range paths {
+		if file, ok := c.Files[path]; ok && !listed[path] {
+			names[file.Name] = true
+			delete(c.Files, path)
+		}
+	}
+	for _, file := range c.Files {
+		delete(names, file.Name)
+	}
+	for name := range names {
+		removeSavedTranscript(name)
+	}
+	return nil
+}
+
+// removeSavedTranscript deletes output/transcriptions/<name> in every format
+func removeSavedTranscript(name string) {
+	for _, suffix := range []string{".txt", ".srt", ".vtt", ".json", ".words.srt", ".words.vtt"} {
+		os.Remove(filepath.Join(transcriptionDir, name+suffix))
+	}
+}
+
+// acceptsSubstitutes reports whether captions or an imported transcript may stand in for a
+// transcription. They can't when a backend or model was asked for, or word timestamps were,
+// since captions have neither.
+func (o TranscriptionOptions) acceptsSubstitutes() bool {
+	return o.Backend == "" && o.Model == "" && !o.Words
+}
+
+// cachedTranscript looks up the transcript of mediaFile for a transcriber. When there is none
+// it returns the entry a new transcript should be stored under. Captions and imports are only
+// used when substitutes is set.
+func cachedTranscript(mediaFile string, transcriber Transcriber, opts TranscriptionOptions, substitutes bool) (*Transcript, *TranscriptionCacheEntry, error) {
+	transcriptionCacheMu.Lock()
+	defer transcriptionCacheMu.Unlock()
+
+	cache, err := LoadTranscriptionCache(transcriptionCacheDir())
+	if err != nil {
+		return nil, nil, err
+	}
+	path, file, err := cache.trackFile(mediaFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := transcriptionCacheKey(file.AudioHash, transcriber.Name(), transcriber.Model(), opts.Language, opts.Words)
+	pending := &TranscriptionCacheEntry{Key: key, AudioHash: file.AudioHash, Backend: transcriber.Name(), Model: transcriber.Model(), Language: opts.Language, Words: opts.Words}
+
+	if entry := cache.find(key, file.AudioHash, substitutes); entry != nil {
+		transcript, err := cache.Get(entry)
+		if err == nil {
+			cache.addFile(entry, path)
+			return transcript, entry, cache.Save()
+		}
+		fmt.Printf("⚠️ Dropping unreadable cache entry %s: %v\n", entry.Key, err)
+		delete(cache.Entries, entry.Key)
+	}
+
+	// Captions saved by name before the video was downloaded have no cache entry yet
+	if !substitutes {
+		return nil, pending, cache.Save()
+	}
+	if saved, _, err := LoadTranscript(file.Name); err == nil && saved != nil &&
+		(saved.Backend == transcriptSourceCaptions || saved.Backend == transcriptSourceImport) &&
+		!cache.nameClaimedElsewhere(file.Name, file.AudioHash) {
+		entry := &TranscriptionCacheEntry{
+			Key:       transcriptionCacheKey(file.AudioHash, saved.Backend, "", saved.Language, false),
+			AudioHash: file.AudioHash,
+			Backend:   saved.Backend,
+			Language:  saved.Language,
+			CreatedAt: time.Now(),
+		}
+		if err := cache.Put(entry, saved, path); err != nil {
+			return nil, nil, err
+		}
+		return saved, entry, cache.Save()
+	}
+
+	return nil, pending, cache.Save()
+}
+
+// storeTranscript adds a finished transcript of mediaFile to the cache
+func storeTranscript(mediaFile string, entry *TranscriptionCacheEntry, transcript *Transcript) error {
+	transcriptionCacheMu.Lock()
+	defer transcriptionCacheMu.Unlock()
+
+	cache, err := LoadTranscriptionCache(transcriptionCacheDir())
+	if err != nil {
+		return err
+	}
+	path, _, err := cache.trackFile(mediaFile)
+	if err != nil {
+		return err
+	}
+	entry.CreatedAt = time.Now()
+	if err := cache.Put(entry, transcript, path); err != nil {
+		return err
+	}
+	return cache.Save()
+}
+
+// cacheTranscriptFor stores a transcript that came from captions or an import as the
+// transcript of mediaFile's audio
+func cacheTranscriptFor(mediaFile string, transcript *Transcript) error {
+	transcriptionCacheMu.Lock()
+	defer transcriptionCacheMu.Unlock()
+
+	cache, err := LoadTranscriptionCache(transcriptionCacheDir())
+	if err != nil {
+		return err
+	}
+	path, file, err := cache.trackFile(mediaFile)
+	if err != nil {
+		return err
+	}
+	entry := &TranscriptionCacheEntry{
+		Key:       transcriptionCacheKey(file.AudioHash, transcript.Backend, "", transcript.Language, false),
+		AudioHash: file.AudioHash,
+		Backend:   transcript.Backend,
+		Language:  transcript.Language,
+		CreatedAt: time.Now(),
+	}
+	if err := cache.Put(entry, transcript, path); err != nil {
+		return err
+	}
+	return cache.Save()
+}
+
+// InspectTranscriptionCache prints the entries for each query, or all entries without one
+func InspectTranscriptionCache(queries []string) error {
+	cache, err := LoadTranscriptionCache(transcriptionCacheDir())
+	if err != nil {
+		return err
+	}
+
+	entries := cache.sortedEntries()
+	if len(queries) > 0 {
+		entries = nil
+		for _, query := range queries {
+			matches := cache.Lookup(query)
+			if len(matches) == 0 {
+				fmt.Printf("No cached transcript for %s\n", query)
+			}
+			entries = append(entries, matches...)
+		}
+	}
+	if len(entries) == 0 {
+		fmt.Println("The transcription cache is empty.")
+		return nil
+	}
+
+	for _, entry := range entries {
+		model := entry.Backend
+		if entry.Model != "" {
+			model += "/" + entry.Model
+		}
+		language := entry.Language
+		if language == "" {
+			language = "auto"
+		}
+		words := ""
+		if entry.Words {
+			words = ", words"
+		}
+		fmt.Printf("🗂️ %s  %s, %s%s, %s\n", entry.Key, model, language, words, entry.CreatedAt.Format("2006-01-02 15:04"))
+		if transcript, err := cache.Get(entry); err == nil {
+			fmt.Printf("   %q\n", truncateText(transcript.Text, 80))
+		}
+		for _, file := range entry.Files {
+			fmt.Printf("   %s\n", file)
+		}
+	}
+	return nil
+}
+
+// InvalidateTranscriptions removes the entries matching the queries, or every entry with all
+func InvalidateTranscriptions(queries []string, all bool) error {
+	transcriptionCacheMu.Lock()
+	defer transcriptionCacheMu.Unlock()
+
+	cache, err := LoadTranscriptionCache(transcriptionCacheDir())
+	if err != nil {
+		return err
+	}
+
+	var entries []*TranscriptionCacheEntry
+	if all {
+		entries = cache.sortedEntries()
+	}
+	for _, query := range queries {
+		matches := cache.Lookup(query)
+		if len(matches) == 0 {
+			return fmt.Errorf("no cached transcript for %s", query)
+		}
+		for _, match := range matches {
+			if !containsEntry(entries, match) {
+				entries = append(entries, match)
+			}
+		}
+	}
+
+	if err := cache.Invalidate(entries); err != nil {
+		return err
+	}
+	if err := cache.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("🗑️ Invalidated %d cached transcript(s)\n", len(entries))
+	return nil
+}
+
+// containsEntry reports whether entries holds entry
+func containsEntry(entries []*TranscriptionCacheEntry, entry *TranscriptionCacheEntry) bool {
+	for _, e := range entries {
+		if e == entry {
+			return true
+		}
+	}
+	return false
+}
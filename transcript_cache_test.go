@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTranscriptionCacheKey(t *testing.T) {
+	base := transcriptionCacheKey("hash", "whisper", "base", "en", false)
+	if len(base) != 32 {
+		t.Errorf("key %q has %d characters, want 32", base, len(base))
+	}
+	if again := transcriptionCacheKey("hash", "whisper", "base", "en", false); again != base {
+		t.Errorf("key is not stable: %q then %q", base, again)
+	}
+	for _, other := range []string{
+		transcriptionCacheKey("other", "whisper", "base", "en", false),
+		transcriptionCacheKey("hash", "openai", "base", "en", false),
+		transcriptionCacheKey("hash", "whisper", "small", "en", false),
+		transcriptionCacheKey("hash", "whisper", "base", "de", false),
+		transcriptionCacheKey("hash", "whisper", "base", "en", true),
+	} {
+		if other == base {
+			t.Errorf("different settings share key %q", base)
+		}
+	}
+}
+
+func TestTranscriptionCacheLookup(t *testing.T) {
+	video, err := filepath.Abs("talk.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	cache := &TranscriptionCache{
+		Entries: map[string]*TranscriptionCacheEntry{
+			"aaaa1111bbbb2222": {Key: "aaaa1111bbbb2222", AudioHash: "h1", CreatedAt: now},
+			"aaaa1111cccc3333": {Key: "aaaa1111cccc3333", AudioHash: "h1", CreatedAt: now.Add(-time.Hour)},
+			"dddd4444eeee5555": {Key: "dddd4444eeee5555", AudioHash: "h2", CreatedAt: now},
+		},
+		Files: map[string]*TranscribedFile{video: {AudioHash: "h1", Name: "talk"}},
+	}
+	keys := func(entries []*TranscriptionCacheEntry) []string {
+		var keys []string
+		for _, entry := range entries {
+			keys = append(keys, entry.Key)
+		}
+		return keys
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"talk.mp4", []string{"aaaa1111cccc3333", "aaaa1111bbbb2222"}},
+		{"dddd4444eeee5555", []string{"dddd4444eeee5555"}},
+		{"aaaa1111cc", []string{"aaaa1111cccc3333"}},
+		{"aaaa1111", []string{"aaaa1111cccc3333", "aaaa1111bbbb2222"}},
+		{"aaaa111", nil},
+		{"a", nil},
+		{"ffff9999", nil},
+		{"other.mp4", nil},
+	}
+	for _, tt := range tests {
+		if got := keys(cache.Lookup(tt.query)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lookup(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestTranscriptionCachePutGet(t *testing.T) {
+	cache := &TranscriptionCache{dir: t.TempDir(), Entries: map[string]*TranscriptionCacheEntry{}, Files: map[string]*TranscribedFile{}}
+	entry := &TranscriptionCacheEntry{Key: "k1", AudioHash: "h1", Backend: "whisper"}
+	transcript := &Transcript{Text: "hello", Segments: []TranscriptSegment{{Start: 0, End: 1, Text: "hello"}}}
+
+	for _, file := range []string{"/b.mp4", "/a.mp4", "/b.mp4"} {
+		if err := cache.Put(entry, transcript, file); err != nil {
+			t.Fatalf("Put returned error: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(entry.Files, []string{"/a.mp4", "/b.mp4"}) {
+		t.Errorf("entry files = %v, want each file once, sorted", entry.Files)
+	}
+	got, err := cache.Get(cache.Entries["k1"])
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, transcript) {
+		t.Errorf("Get() = %+v, want %+v", got, transcript)
+	}
+}
+
+func TestTranscriptionCacheInvalidate(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.MkdirAll(transcriptionDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"talk", "intro"} {
+		if err := os.WriteFile(filepath.Join(transcriptionDir, name+".txt"), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// talk.mp4 was transcribed with two backends; a/intro.mp4 and b/intro.mp4 share
+	// the saved name "intro"
+	cache := &TranscriptionCache{
+		dir: t.TempDir(),
+		Entries: map[string]*TranscriptionCacheEntry{
+			"k1": {Key: "k1", AudioHash: "h1", Files: []string{"/talk.mp4"}},
+			"k2": {Key: "k2", AudioHash: "h1", Files: []string{"/talk.mp4"}},
+			"k3": {Key: "k3", AudioHash: "h2", Files: []string{"/a/intro.mp4"}},
+			"k4": {Key: "k4", AudioHash: "h3", Files: []string{"/b/intro.mp4"}},
+		},
+		Files: map[string]*TranscribedFile{
+			"/talk.mp4":    {AudioHash: "h1", Name: "talk"},
+			"/a/intro.mp4": {AudioHash: "h2", Name: "intro"},
+			"/b/intro.mp4": {AudioHash: "h3", Name: "intro"},
+		},
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(transcriptionDir, name+".txt"))
+		return err == nil
+	}
+
+	if err := cache.Invalidate([]*TranscriptionCacheEntry{cache.Entries["k1"], cache.Entries["k3"]}); err != nil {
+		t.Fatalf("Invalidate returned error: %v", err)
+	}
+	if cache.Files["/talk.mp4"] == nil || !exists("talk") {
+		t.Error("talk.mp4 dropped although entry k2 still lists it")
+	}
+	if cache.Files["/a/intro.mp4"] != nil {
+		t.Error("a/intro.mp4 kept although no entry lists it")
+	}
+	if !exists("intro") {
+		t.Error("intro transcript removed although b/intro.mp4 still uses the name")
+	}
+
+	if err := cache.Invalidate([]*TranscriptionCacheEntry{cache.Entries["k2"], cache.Entries["k4"]}); err != nil {
+		t.Fatalf("Invalidate returned error: %v", err)
+	}
+	if len(cache.Files) != 0 || exists("talk") || exists("intro") {
+		t.Errorf("files %v and saved transcripts left after invalidating every entry", cache.Files)
+	}
+}
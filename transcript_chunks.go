@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Silence detection settings for choosing chunk boundaries. Cutting inside a pause keeps
+// words whole; half a second is long enough to be between sentences.
+const (
+	chunkSilenceThresholdDB = -35.0
+	chunkSilenceDuration    = 0.5
+)
+
+// TranscriptChunk is one stretch of a long recording transcribed on its own
+type TranscriptChunk struct {
+	Start float64 // seconds into the recording
+	End   float64
+	File  string
+}
+
+// chunkBoundaries picks cut points near every target seconds, preferring the middle of a
+// pause between talking intervals and cutting mid-speech only when no pause is within half a
+// target of the ideal spot
+func chunkBoundaries(talking []SilenceInterval, duration, target float64) []float64 {
+	var pauses []float64
+	for i := 1; i < len(talking); i++ {
+		if talking[i].Start > talking[i-1].End {
+			pauses = append(pauses, (talking[i-1].End+talking[i].Start)/2)
+		}
+	}
+	sort.Float64s(pauses)
+
+	var cuts []float64
+	start := 0.0
+	for duration-start > target*1.5 {
+		ideal := start + target
+		cut := ideal
+		best := target / 2
+		for _, pause := range pauses {
+			if distance := math.Abs(pause - ideal); pause > start && distance <= best {
+				cut, best = pause, distance
+			}
+		}
+		cuts = append(cuts, cut)
+		start = cut
+	}
+	return cuts
+}
+
+// detectTalking runs ffmpeg's silencedetect over an audio file and returns the talking intervals
+func detectTalking(audioFile string, duration float64) ([]SilenceInterval, error) {
+	cmd := exec.Command("ffmpeg",
+		"-i", audioFile,
+		"-af", fmt.Sprintf("silencedetect=n=%fdB:d=%f", chunkSilenceThresholdDB, chunkSilenceDuration),
+		"-f", "null", "-",
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("error detecting silence: %v\n%s", err, output)
+	}
+	return ParseSilenceOutputToTalkingIntervals(string(output), duration)
+}
+
+// splitAudioIntoChunks extracts the audio of mediaFile and cuts it at silences into chunks
+// of about target seconds
+func splitAudioIntoChunks(mediaFile, workDir string, duration, target float64) ([]TranscriptChunk, error) {
+	audioFile := filepath.Join(workDir, "audio.flac")
+	if err := ExtractAudio(mediaFile, audioFile); err != nil {
+		return nil, err
+	}
+
+	talking, err := detectTalking(audioFile, duration)
+	if err != nil {
+		// Fixed-length chunks still work, they just may cut a word in half
+		fmt.Printf("⚠️ %v; cutting at fixed lengths\n", err)
+	}
+
+	starts := append([]float64{0}, chunkBoundaries(talking, duration, target)...)
+	var chunks []TranscriptChunk
+	for i, start := range starts {
+		end := duration
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		chunk := TranscriptChunk{Start: start, End: end, File: filepath.Join(workDir, fmt.Sprintf("chunk_%03d.flac", i))}
+		cmd := exec.Command("ffmpeg", "-y", "-v", "error",
+			"-ss", fmt.Sprintf("%.3f", start), "-i", audioFile,
+			"-t", fmt.Sprintf("%.3f", end-start), "-c:a", "flac", chunk.File)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("failed to cut audio chunk %d: %v\n%s", i, err, output)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// transcribeWithRetries runs one transcription, backing off 5s, 10s, 20s... between attempts
+func transcribeWithRetries(transcriber Transcriber, file, label string, retries int) (*Transcript, error) {
+	backoff := 5 * time.Second
+	var lastErr error
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		transcript, err := transcriber.Transcribe(file)
+		if err == nil {
+			return transcript, nil
+		}
+		lastErr = err
+		if attempt <= retries {
+			fmt.Printf("❌ Transcribing %s failed (attempt %d/%d): %v\nRetrying in %s...\n", label, attempt, retries+1, err, backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return nil, lastErr
+}
+
+// stitchTranscripts joins chunk transcripts, shifting their timestamps by each chunk's start
+func stitchTranscripts(chunks []TranscriptChunk, parts []*Transcript) *Transcript {
+	stitched := &Transcript{}
+	var texts []string
+	for i, part := range parts {
+		offset := chunks[i].Start
+		if stitched.Language == "" {
+			stitched.Language = part.Language
+		}
+		if text := strings.TrimSpace(part.Text); text != "" {
+			texts = append(texts, text)
+		}
+		for _, segment := range part.Segments {
+			segment.Start += offset
+			segment.End += offset
+			words := make([]TranscriptWord, len(segment.Words))
+			for j, word := range segment.Words {
+				word.Start += offset
+				word.End += offset
+				words[j] = word
+			}
+			if len(words) == 0 {
+				words = nil
+			}
+			segment.Words = words
+			stitched.Segments = append(stitched.Segments, segment)
+		}
+	}
+	stitched.Text = strings.Join(texts, " ")
+	return stitched
+}
+
+// chunkWorkDir is where the chunks of a recording and their finished transcripts are kept
+// until all of them are done, so a failed run can be resumed. key is the transcription cache
+// key, which covers the audio and the transcriber settings.
+func chunkWorkDir(key string, target float64) string {
+	return filepath.Join(transcriptionCacheDir(), "chunks", fmt.Sprintf("%s_%.0fs", key, target))
+}
+
+// loadChunks returns the chunks a previous run cut into workDir, or nil if it didn't finish cutting
+func loadChunks(workDir string) []TranscriptChunk {
+	data, err := os.ReadFile(filepath.Join(workDir, "chunks.json"))
+	if err != nil {
+		return nil
+	}
+	var chunks []TranscriptChunk
+	if err := json.Unmarshal(data, &chunks); err != nil {
+		return nil
+	}
+	for _, chunk := range chunks {
+		if _, err := os.Stat(chunk.File); err != nil {
+			return nil
+		}
+	}
+	return chunks
+}
+
+// chunkTranscriptFile is where the finished transcript of chunk i is kept
+func chunkTranscriptFile(workDir string, i int) string {
+	return filepath.Join(workDir, fmt.Sprintf("chunk_%03d.json", i))
+}
+
+// defaultChunkWorkers is how many chunks are transcribed at once when -workers isn't given.
+// Local models already use every core, so running several only makes them compete.
+func defaultChunkWorkers(backend string) int {
+	if backend == "openai" {
+		return 4
+	}
+	return 1
+}
+
+// transcribeChunked transcribes a long recording as chunks split at silences, opts.Workers at
+// a time, retrying each failed chunk on its own. Finished chunks are kept under key until the
+// whole recording is done, so running it again redoes only the chunks that failed.
+// Recordings shorter than one and a half chunks, and every recording when only one worker
+// runs, are transcribed in one call: chunking only pays off when chunks run in parallel.
+func transcribeChunked(transcriber Transcriber, mediaFile, key string, opts TranscriptionOptions) (*Transcript, error) {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = defaultChunkWorkers(transcriber.Name())
+	}
+	target := opts.ChunkLength.Seconds()
+	if workers < 2 {
+		target = 0
+	}
+	var duration float64
+	if target > 0 {
+		if info, err := ProbeMedia(mediaFile); err == nil {
+			duration = info.Duration
+		} else {
+			fmt.Printf("⚠️ Could not probe %s, transcribing in one piece: %v\n", mediaFile, err)
+		}
+	}
+	if target <= 0 || duration <= target*1.5 {
+		return transcribeWithRetries(transcriber, mediaFile, mediaFile, opts.Retries)
+	}
+
+	workDir := chunkWorkDir(key, target)
+	chunks := loadChunks(workDir)
+	if chunks == nil {
+		// Start over from an empty directory; a run that failed while cutting may have left
+		// files behind that ffmpeg would refuse to overwrite
+		if err := os.RemoveAll(workDir); err != nil {
+			return nil, fmt.Errorf("failed to clear chunk directory: %v", err)
+		}
+		if err := os.MkdirAll(workDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create chunk directory: %v", err)
+		}
+		var err error
+		if chunks, err = splitAudioIntoChunks(mediaFile, workDir, duration, target); err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(chunks, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(workDir, "chunks.json"), data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write chunk list: %v", err)
+		}
+	}
+
+	parts := make([]*Transcript, len(chunks))
+	var pending []int
+	for i := range chunks {
+		if part, err := ReadTranscriptFile(chunkTranscriptFile(workDir, i)); err == nil {
+			parts[i] = part
+		} else {
+			pending = append(pending, i)
+		}
+	}
+	if finished := len(chunks) - len(pending); finished > 0 {
+		fmt.Printf("♻️ Resuming: %d of %d chunks already transcribed\n", finished, len(chunks))
+	}
+
+	fmt.Printf("🧩 Transcribing %d chunks of about %s, %d at a time...\n", len(pending), opts.ChunkLength, workers)
+
+	errs := make([]error, len(chunks))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	done := len(chunks) - len(pending)
+	var doneMu sync.Mutex
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				label := fmt.Sprintf("chunk %d (%s-%s)", i+1, formatSRTTime(chunks[i].Start), formatSRTTime(chunks[i].End))
+				parts[i], errs[i] = transcribeWithRetries(transcriber, chunks[i].File, label, opts.Retries)
+				if errs[i] != nil {
+					continue
+				}
+				if content, err := RenderTranscript(parts[i], "json", false); err == nil {
+					if err := os.WriteFile(chunkTranscriptFile(workDir, i), []byte(content), 0644); err != nil {
+						fmt.Printf("⚠️ Could not keep transcript of %s: %v\n", label, err)
+					}
+				}
+				doneMu.Lock()
+				done++
+				fmt.Printf("✅ %s done (%d/%d)\n", label, done, len(chunks))
+				doneMu.Unlock()
+			}
+		}()
+	}
+
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("chunk %d: %v", i+1, err))
+		}
+	}
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("%d of %d chunks failed (finished chunks are kept in %s, run again to redo only the failed ones):\n%s",
+			len(failed), len(chunks), workDir, strings.Join(failed, "\n"))
+	}
+	os.RemoveAll(workDir)
+	return stitchTranscripts(chunks, parts), nil
+}
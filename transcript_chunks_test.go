@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChunkBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		talking  []SilenceInterval
+		duration float64
+		target   float64
+		want     []float64
+	}{
+		{"short recording stays whole", nil, 80, 60, nil},
+		{"no pauses cuts at the target", nil, 200, 60, []float64{60, 120}},
+		{"cuts in the nearest pause", []SilenceInterval{{0, 55}, {58, 120}, {125, 200}}, 200, 60, []float64{56.5, 122.5}},
+		{"pauses too far away are ignored", []SilenceInterval{{0, 10}, {12, 300}}, 300, 100, []float64{100, 200}},
+		{"last chunk may run to one and a half targets", nil, 150, 60, []float64{60}},
+	}
+	for _, tt := range tests {
+		if got := chunkBoundaries(tt.talking, tt.duration, tt.target); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: chunkBoundaries() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStitchTranscripts(t *testing.T) {
+	chunks := []TranscriptChunk{{Start: 0, End: 60}, {Start: 60, End: 120}, {Start: 120, End: 150}}
+	parts := []*Transcript{
+		{Text: " first ", Language: "en", Segments: []TranscriptSegment{
+			{Start: 1, End: 2, Text: "first", Words: []TranscriptWord{{"first", 1, 2}}},
+		}},
+		{Text: "", Language: "de"},
+		{Text: "second", Segments: []TranscriptSegment{{Start: 0.5, End: 3, Text: "second"}}},
+	}
+	want := &Transcript{
+		Text:     "first second",
+		Language: "en",
+		Segments: []TranscriptSegment{
+			{Start: 1, End: 2, Text: "first", Words: []TranscriptWord{{"first", 1, 2}}},
+			{Start: 120.5, End: 123, Text: "second"},
+		},
+	}
+	if got := stitchTranscripts(chunks, parts); !reflect.DeepEqual(got, want) {
+		t.Errorf("stitchTranscripts() = %+v, want %+v", got, want)
+	}
+	if parts[0].Segments[0].Words[0].Start != 1 {
+		t.Error("stitchTranscripts modified the chunk transcripts")
+	}
+}
+
+func TestStitchTranscriptsShiftsWords(t *testing.T) {
+	chunks := []TranscriptChunk{{Start: 0, End: 600}, {Start: 600, End: 1200}}
+	parts := []*Transcript{
+		{Text: "a"},
+		{Text: "b c", Segments: []TranscriptSegment{{Start: 1, End: 3, Text: "b c", Words: []TranscriptWord{{"b", 1, 2}, {"c", 2, 3}}}}},
+	}
+	got := stitchTranscripts(chunks, parts)
+	want := []TranscriptWord{{"b", 601, 602}, {"c", 602, 603}}
+	if len(got.Segments) != 1 || !reflect.DeepEqual(got.Segments[0].Words, want) {
+		t.Errorf("stitched words = %+v, want %+v", got.Segments, want)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// transcriptFormats are the file formats transcripts are saved in and read from
+var transcriptFormats = []string{"txt", "srt", "vtt", "json"}
+
+const transcriptionDir = "./output/transcriptions"
+
+// formatVTTTime renders seconds as HH:MM:SS.mmm
+func formatVTTTime(seconds float64) string {
+	return strings.Replace(formatSRTTime(seconds), ",", ".", 1)
+}
+
+// renderVTT writes cues as a WebVTT document
+func renderVTT(cues []captionCue) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", formatVTTTime(cue.Start), formatVTTTime(cue.End), strings.Join(cue.Lines, "\n"))
+	}
+	return b.String()
+}
+
+// parseSRTCues reads the cues of an SRT file
+func parseSRTCues(content string) ([]captionCue, error) {
+	// SRT differs from WebVTT in its millisecond separator and the missing header
+	var vtt strings.Builder
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if strings.Contains(line, "-->") {
+			line = strings.ReplaceAll(line, ",", ".")
+		}
+		vtt.WriteString(line + "\n")
+	}
+	return parseVTTCues(vtt.String())
+}
+
+// transcriptFromCues builds a transcript with one segment per caption cue
+func transcriptFromCues(cues []captionCue) *Transcript {
+	transcript := &Transcript{}
+	var texts []string
+	for _, cue := range cues {
+		text := strings.Join(cue.Lines, " ")
+		transcript.Segments = append(transcript.Segments, TranscriptSegment{Start: cue.Start, End: cue.End, Text: text})
+		texts = append(texts, text)
+	}
+	transcript.Text = strings.Join(texts, " ")
+	return transcript
+}
+
+// HasWords reports whether the transcript has word-level timestamps
+func (t *Transcript) HasWords() bool {
+	for _, segment := range t.Segments {
+		if len(segment.Words) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// cues returns one caption cue per segment, or per word when words is set
+func (t *Transcript) cues(words bool) []captionCue {
+	var cues []captionCue
+	for _, segment := range t.Segments {
+		if !words {
+			cues = append(cues, captionCue{Start: segment.Start, End: segment.End, Lines: []string{segment.Text}})
+			continue
+		}
+		for _, word := range segment.Words {
+			cues = append(cues, captionCue{Start: word.Start, End: word.End, Lines: []string{word.Word}})
+		}
+	}
+	return cues
+}
+
+// RenderTranscript writes the transcript as txt, srt, vtt or json. Subtitle formats get
+// one cue per segment, or per word when words is set.
+func RenderTranscript(t *Transcript, format string, words bool) (string, error) {
+	switch format {
+	case "txt":
+		return strings.TrimSpace(t.Text) + "\n", nil
+	case "srt":
+		return renderSRT(t.cues(words)), nil
+	case "vtt":
+		return renderVTT(t.cues(words)), nil
+	case "json":
+		data, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	}
+	return "", fmt.Errorf("unknown transcript format %q (available: %s)", format, strings.Join(transcriptFormats, ", "))
+}
+
+// ParseTranscript reads a transcript in txt, srt, vtt or json format. JSON may be this
+// tool's own schema or whisper.cpp's --output-json file.
+func ParseTranscript(content, format string) (*Transcript, error) {
+	switch format {
+	case "txt":
+		return &Transcript{Text: strings.TrimSpace(content)}, nil
+	case "srt", "vtt":
+		parse := parseVTTCues
+		if format == "srt" {
+			parse = parseSRTCues
+		}
+		cues, err := parse(content)
+		if err != nil {
+			return nil, err
+		}
+		return transcriptFromCues(cues), nil
+	case "json":
+		var probe struct {
+			Transcription json.RawMessage `json:"transcription"`
+		}
+		if err := json.Unmarshal([]byte(content), &probe); err != nil {
+			return nil, err
+		}
+		if probe.Transcription != nil {
+			return parseWhisperCppJSON([]byte(content))
+		}
+		var transcript Transcript
+		if err := json.Unmarshal([]byte(content), &transcript); err != nil {
+			return nil, err
+		}
+		if transcript.Text == "" {
+			var texts []string
+			for _, segment := range transcript.Segments {
+				texts = append(texts, segment.Text)
+			}
+			transcript.Text = strings.Join(texts, " ")
+		}
+		return &transcript, nil
+	}
+	return nil, fmt.Errorf("unknown transcript format %q (available: %s)", format, strings.Join(transcriptFormats, ", "))
+}
+
+// ReadTranscriptFile parses a transcript file, taking the format from its extension
+func ReadTranscriptFile(path string) (*Transcript, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %v", err)
+	}
+	transcript, err := ParseTranscript(string(content), strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return transcript, nil
+}
+
+// SaveTranscript writes the transcript to output/transcriptions/<name> in every format. The
+// .txt is what GenerateTitlesAndDescriptions reads and the .json what timing-aware steps read.
+// With word timestamps, <name>.words.srt and <name>.words.vtt get one cue per word.
+func SaveTranscript(t *Transcript, name string) ([]string, error) {
+	if err := os.MkdirAll(transcriptionDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create transcriptions directory: %v", err)
+	}
+	// Clear an earlier save so formats this transcript doesn't get aren't left stale
+	removeSavedTranscript(name)
+
+	type output struct {
+		suffix, format string
+		words          bool
+	}
+	outputs := []output{{".txt", "txt", false}, {".srt", "srt", false}, {".vtt", "vtt", false}, {".json", "json", false}}
+	if t.HasWords() {
+		outputs = append(outputs, output{".words.srt", "srt", true}, output{".words.vtt", "vtt", true})
+	}
+	if len(t.Segments) == 0 {
+		outputs = outputs[:1] // no timing to write
+	}
+
+	var files []string
+	for _, o := range outputs {
+		content, err := RenderTranscript(t, o.format, o.words)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(transcriptionDir, name+o.suffix)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s transcript: %v", o.format, err)
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// LoadTranscript returns the stored transcript called name, preferring the format with the
+// most detail, or nil when there is none
+func LoadTranscript(name string) (*Transcript, string, error) {
+	for _, format := range []string{"json", "srt", "vtt", "txt"} {
+		path := filepath.Join(transcriptionDir, name+"."+format)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		transcript, err := ReadTranscriptFile(path)
+		return transcript, path, err
+	}
+	return nil, "", nil
+}
+
+// ImportTranscript stores a transcript made elsewhere, such as a subtitle file, as the
+// transcript of mediaFile so later steps use it instead of transcribing. Without the media
+// file it is kept by name and cached once the file is transcribed.
+func ImportTranscript(path, mediaFile string) (*Transcript, error) {
+	transcript, err := ReadTranscriptFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(transcript.Text) == "" {
+		return nil, fmt.Errorf("transcript %s is empty", path)
+	}
+	transcript.Backend = transcriptSourceImport
+	files, err := SaveTranscript(transcript, captionNameFor(mediaFile))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(mediaFile); err == nil {
+		if err := cacheTranscriptFor(mediaFile, transcript); err != nil {
+			fmt.Printf("⚠️ Could not cache transcript: %v\n", err)
+		}
+	}
+	fmt.Printf("✅ Imported %s as %s\n", path, strings.Join(files, ", "))
+	return transcript, nil
+}
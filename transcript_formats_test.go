@@ -0,0 +1,186 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleTranscript has two segments with word timestamps
+func sampleTranscript() *Transcript {
+	return &Transcript{
+		Text:     "Hello there. General Kenobi!",
+		Language: "en",
+		Segments: []TranscriptSegment{
+			{Start: 0, End: 1.5, Text: "Hello there.", Words: []TranscriptWord{{"Hello", 0, 0.6}, {"there.", 0.7, 1.5}}},
+			{Start: 2, End: 3.25, Text: "General Kenobi!", Words: []TranscriptWord{{"General", 2, 2.5}, {"Kenobi!", 2.6, 3.25}}},
+		},
+	}
+}
+
+func TestParseSRTCues(t *testing.T) {
+	content := "1\r\n00:00:01,000 --> 00:00:02,500\r\nHello, world\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nfirst line\r\nsecond line\r\n"
+	want := []captionCue{
+		{Start: 1, End: 2.5, Lines: []string{"Hello, world"}},
+		{Start: 3, End: 4, Lines: []string{"first line", "second line"}},
+	}
+	got, err := parseSRTCues(content)
+	if err != nil {
+		t.Fatalf("parseSRTCues returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSRTCues() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRenderAndParseTranscript(t *testing.T) {
+	original := sampleTranscript()
+	segmentsOnly := []TranscriptSegment{
+		{Start: 0, End: 1.5, Text: "Hello there."},
+		{Start: 2, End: 3.25, Text: "General Kenobi!"},
+	}
+	tests := []struct {
+		format string
+		want   *Transcript
+	}{
+		{"txt", &Transcript{Text: "Hello there. General Kenobi!"}},
+		{"srt", &Transcript{Text: "Hello there. General Kenobi!", Segments: segmentsOnly}},
+		{"vtt", &Transcript{Text: "Hello there. General Kenobi!", Segments: segmentsOnly}},
+		{"json", original},
+	}
+	for _, tt := range tests {
+		content, err := RenderTranscript(original, tt.format, false)
+		if err != nil {
+			t.Fatalf("RenderTranscript(%s) returned error: %v", tt.format, err)
+		}
+		got, err := ParseTranscript(content, tt.format)
+		if err != nil {
+			t.Fatalf("ParseTranscript(%s) returned error: %v\n%s", tt.format, err, content)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s round trip = %+v, want %+v", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestRenderTranscriptWords(t *testing.T) {
+	got, err := RenderTranscript(sampleTranscript(), "vtt", true)
+	if err != nil {
+		t.Fatalf("RenderTranscript returned error: %v", err)
+	}
+	want := "WEBVTT\n\n" +
+		"00:00:00.000 --> 00:00:00.600\nHello\n\n" +
+		"00:00:00.700 --> 00:00:01.500\nthere.\n\n" +
+		"00:00:02.000 --> 00:00:02.500\nGeneral\n\n" +
+		"00:00:02.600 --> 00:00:03.250\nKenobi!\n\n"
+	if got != want {
+		t.Errorf("RenderTranscript(vtt, words) =\n%s\nwant\n%s", got, want)
+	}
+
+	srt, _ := RenderTranscript(sampleTranscript(), "srt", false)
+	if !strings.HasPrefix(srt, "1\n00:00:00,000 --> 00:00:01,500\nHello there.\n\n2\n") {
+		t.Errorf("RenderTranscript(srt) =\n%s", srt)
+	}
+}
+
+func TestParseTranscriptErrors(t *testing.T) {
+	if _, err := RenderTranscript(sampleTranscript(), "docx", false); err == nil {
+		t.Error("RenderTranscript accepted an unknown format")
+	}
+	if _, err := ParseTranscript("text", "docx"); err == nil {
+		t.Error("ParseTranscript accepted an unknown format")
+	}
+	if _, err := ParseTranscript("{not json", "json"); err == nil {
+		t.Error("ParseTranscript accepted invalid JSON")
+	}
+}
+
+func TestParseTranscriptJSONWithoutText(t *testing.T) {
+	got, err := ParseTranscript(`{"segments":[{"start":0,"end":1,"text":"one"},{"start":1,"end":2,"text":"two"}]}`, "json")
+	if err != nil {
+		t.Fatalf("ParseTranscript returned error: %v", err)
+	}
+	if got.Text != "one two" {
+		t.Errorf("Text = %q, want it joined from the segments", got.Text)
+	}
+}
+
+func TestParseWhisperCppJSON(t *testing.T) {
+	content := `{
+  "result": {"language": "en"},
+  "transcription": [
+    {
+      "offsets": {"from": 0, "to": 2000},
+      "text": " Hello wonderful world",
+      "tokens": [
+        {"text": "[_BEG_]", "offsets": {"from": 0, "to": 0}},
+        {"text": " Hello", "offsets": {"from": 0, "to": 500}},
+        {"text": " wonder", "offsets": {"from": 600, "to": 900}},
+        {"text": "ful", "offsets": {"from": 900, "to": 1200}},
+        {"text": " world", "offsets": {"from": 1300, "to": 2000}},
+        {"text": "[_TT_100]", "offsets": {"from": 2000, "to": 2000}}
+      ]
+    },
+    {"offsets": {"from": 2000, "to": 2500}, "text": "  "},
+    {"offsets": {"from": 2500, "to": 4000}, "text": " No tokens here."}
+  ]
+}`
+	want := &Transcript{
+		Text:     "Hello wonderful world No tokens here.",
+		Language: "en",
+		Segments: []TranscriptSegment{
+			{Start: 0, End: 2, Text: "Hello wonderful world", Words: []TranscriptWord{{"Hello", 0, 0.5}, {"wonderful", 0.6, 1.2}, {"world", 1.3, 2}}},
+			{Start: 2.5, End: 4, Text: "No tokens here."},
+		},
+	}
+	// ParseTranscript recognizes whisper.cpp output by its "transcription" key
+	got, err := ParseTranscript(content, "json")
+	if err != nil {
+		t.Fatalf("ParseTranscript returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTranscript(whisper.cpp json) = %+v, want %+v", got, want)
+	}
+}
+
+func TestAssignWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []TranscriptSegment
+		words    []TranscriptWord
+		want     [][]TranscriptWord
+	}{
+		{
+			"words go to the segment they start in",
+			[]TranscriptSegment{{Start: 0, End: 2}, {Start: 2, End: 4}, {Start: 4, End: 6}},
+			[]TranscriptWord{{" one", 0.1, 0.5}, {"two ", 1.9, 2.1}, {"three", 2, 2.5}, {"four", 5, 5.5}},
+			[][]TranscriptWord{{{"one", 0.1, 0.5}, {"two", 1.9, 2.1}}, {{"three", 2, 2.5}}, {{"four", 5, 5.5}}},
+		},
+		{
+			"words after the last segment join it",
+			[]TranscriptSegment{{Start: 0, End: 1}},
+			[]TranscriptWord{{"late", 3, 3.5}},
+			[][]TranscriptWord{{{"late", 3, 3.5}}},
+		},
+		{"no segments", nil, []TranscriptWord{{"lost", 0, 1}}, nil},
+	}
+	for _, tt := range tests {
+		assignWords(tt.segments, tt.words)
+		var got [][]TranscriptWord
+		for _, segment := range tt.segments {
+			got = append(got, segment.Words)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: assignWords() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHasWords(t *testing.T) {
+	if !sampleTranscript().HasWords() {
+		t.Error("HasWords() = false for a transcript with word timestamps")
+	}
+	if (&Transcript{Segments: []TranscriptSegment{{Start: 0, End: 1, Text: "x"}}}).HasWords() {
+		t.Error("HasWords() = true for a transcript without word timestamps")
+	}
+}